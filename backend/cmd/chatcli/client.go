@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fasthttp/websocket"
+
+	ws "github.com/napat2224/socket-programming-chat-app/internal/services/websocket"
+)
+
+// client talks to the chat server: REST for room lists and history, the
+// WebSocket for everything live.
+type client struct {
+	baseURL string
+	token   string
+	test    bool
+	http    *http.Client
+
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+}
+
+type publicRoom struct {
+	ID           string `json:"id"`
+	RoomName     string `json:"roomName"`
+	Topic        string `json:"topic"`
+	IsJoined     bool   `json:"isJoined"`
+	MemberNumber int    `json:"memberNumber"`
+	OnlineNumber int    `json:"onlineNumber"`
+}
+
+type historyMessage struct {
+	ID         string   `json:"id"`
+	RoomID     string   `json:"roomId"`
+	SenderID   string   `json:"senderId"`
+	Content    string   `json:"content"`
+	ReplyTo    string   `json:"replyTo"`
+	Reactions  []string `json:"reactions"`
+	CreatedAt  string   `json:"createdAt"`
+	SenderName string   `json:"senderName"`
+}
+
+func newClient(baseURL, token string, test bool) *client {
+	return &client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		test:    test,
+		http:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (c *client) query() url.Values {
+	q := url.Values{}
+	if c.test {
+		q.Set("mode", "test")
+	} else {
+		q.Set("token", c.token)
+	}
+	return q
+}
+
+func (c *client) getJSON(path string, params url.Values, out any) error {
+	q := c.query()
+	for k, v := range params {
+		q[k] = v
+	}
+	resp, err := c.http.Get(c.baseURL + path + "?" + q.Encode())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			Error string `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		return fmt.Errorf("%s: %s %s", path, resp.Status, body.Error)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (c *client) publicRooms(search string) ([]publicRoom, error) {
+	var body struct {
+		Data []publicRoom `json:"data"`
+	}
+	params := url.Values{}
+	if search != "" {
+		params.Set("q", search)
+	}
+	err := c.getJSON("/api/rooms/public", params, &body)
+	return body.Data, err
+}
+
+func (c *client) myRooms() ([]publicRoom, error) {
+	var body struct {
+		Data []publicRoom `json:"data"`
+	}
+	err := c.getJSON("/api/rooms/mine", nil, &body)
+	return body.Data, err
+}
+
+func (c *client) history(roomID string) ([]historyMessage, error) {
+	var body struct {
+		Data []historyMessage `json:"data"`
+	}
+	err := c.getJSON("/api/rooms/"+url.PathEscape(roomID)+"/messages", nil, &body)
+	return body.Data, err
+}
+
+func (c *client) dial(device string) error {
+	u, err := url.Parse(c.baseURL)
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	default:
+		u.Scheme = "ws"
+	}
+	u.Path = "/ws"
+	q := c.query()
+	q.Set("device", device)
+	u.RawQuery = q.Encode()
+
+	header := http.Header{}
+	header.Set("User-Agent", "chatcli")
+	conn, _, err := websocket.DefaultDialer.Dial(u.String(), header)
+	if err != nil {
+		return err
+	}
+	c.conn = conn
+	return nil
+}
+
+func (c *client) send(t ws.MessageType, data any) error {
+	payload := ws.MustMarshal(ws.WsMessage{
+		Type: t,
+		Data: ws.MustMarshal(data),
+	})
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.conn.WriteMessage(websocket.TextMessage, payload)
+}
+
+// readLoop delivers envelopes until the socket closes.
+func (c *client) readLoop(out chan<- ws.WsMessage) error {
+	defer close(out)
+	for {
+		_, raw, err := c.conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		var envelope ws.WsMessage
+		if err := json.Unmarshal(raw, &envelope); err != nil {
+			continue
+		}
+		out <- envelope
+	}
+}
+
+func (c *client) close() {
+	if c.conn == nil {
+		return
+	}
+	c.writeMu.Lock()
+	_ = c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "bye"))
+	c.writeMu.Unlock()
+	_ = c.conn.Close()
+}
@@ -0,0 +1,457 @@
+// Command chatcli is a terminal client for the chat server. It logs in with
+// a Firebase ID token (or test mode), lists and joins rooms over REST and the
+// WebSocket, and renders live messages, reactions and presence.
+//
+//	go run ./cmd/chatcli -server http://localhost:8080 -token $ID_TOKEN
+//	go run ./cmd/chatcli -test
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/napat2224/socket-programming-chat-app/internal/domain"
+	ws "github.com/napat2224/socket-programming-chat-app/internal/services/websocket"
+)
+
+const (
+	historySize = 50
+	helpText    = `commands:
+  /rooms [search]        list public rooms
+  /mine                  list your rooms
+  /join <room|#n>        join a room and make it current
+  /room <room|#n>        switch the current room and load its history
+  /reply <#n> <text>     reply to message #n
+  /react <#n> <reaction> react to message #n (like, dislike, love)
+  /help                  show this help
+  /quit                  leave
+anything else is sent to the current room`
+)
+
+var reactionNames = map[string]domain.ReactionType{
+	"like":    domain.ReactionLike,
+	"dislike": domain.ReactionDislike,
+	"love":    domain.ReactionLove,
+}
+
+// chat holds the client-side state the commands and renderers share.
+type chat struct {
+	client *client
+	screen *screen
+
+	mu       sync.Mutex
+	current  string
+	rooms    map[string]string // room id -> name
+	listed   []string          // room ids from the last listing, for #n
+	messages []shownMessage    // every message shown so far, for #n
+	names    map[string]string // user id -> name
+}
+
+type shownMessage struct {
+	id      string
+	content string
+}
+
+func main() {
+	server := flag.String("server", "http://localhost:8080", "chat server base URL")
+	token := flag.String("token", os.Getenv("CHAT_TOKEN"), "Firebase ID token (defaults to $CHAT_TOKEN)")
+	test := flag.Bool("test", false, "log in with the server's test mode instead of a token")
+	device := flag.String("device", "chatcli", "device label shown in the session list")
+	flag.Parse()
+
+	if *token == "" && !*test {
+		fmt.Fprintln(os.Stderr, "chatcli: a -token or -test is required")
+		os.Exit(2)
+	}
+
+	c := &chat{
+		client: newClient(*server, *token, *test),
+		rooms:  map[string]string{},
+		names:  map[string]string{},
+	}
+	if err := c.client.dial(*device); err != nil {
+		log.Fatalf("chatcli: connect: %v", err)
+	}
+	c.screen = newScreen()
+	defer c.screen.Close()
+	defer c.client.close()
+
+	c.screen.Println("connected to %s, type /help for commands", *server)
+	c.refreshStatus()
+	if rooms, err := c.client.myRooms(); err == nil {
+		c.rememberRooms(rooms)
+	}
+
+	events := make(chan ws.WsMessage, 64)
+	go func() {
+		if err := c.client.readLoop(events); err != nil {
+			c.screen.Println("! connection closed: %v", err)
+		}
+	}()
+	go func() {
+		for envelope := range events {
+			c.render(envelope)
+		}
+	}()
+
+	input := bufio.NewScanner(os.Stdin)
+	for input.Scan() {
+		line := strings.TrimSpace(input.Text())
+		c.screen.Prompt()
+		if line == "" {
+			continue
+		}
+		if !c.command(line) {
+			return
+		}
+	}
+}
+
+// command runs one input line and reports whether to keep going.
+func (c *chat) command(line string) bool {
+	if !strings.HasPrefix(line, "/") {
+		c.sendText(line, nil)
+		return true
+	}
+
+	name, rest, _ := strings.Cut(line, " ")
+	rest = strings.TrimSpace(rest)
+	switch name {
+	case "/quit", "/exit":
+		return false
+	case "/help":
+		for _, l := range strings.Split(helpText, "\n") {
+			c.screen.Println("%s", l)
+		}
+	case "/rooms":
+		rooms, err := c.client.publicRooms(rest)
+		if err != nil {
+			c.screen.Println("! %v", err)
+			break
+		}
+		c.listRooms(rooms)
+	case "/mine":
+		rooms, err := c.client.myRooms()
+		if err != nil {
+			c.screen.Println("! %v", err)
+			break
+		}
+		c.listRooms(rooms)
+	case "/join":
+		roomID := c.resolveRoom(rest)
+		if roomID == "" {
+			c.screen.Println("! usage: /join <room|#n>")
+			break
+		}
+		if err := c.client.send(ws.TypeJoinRoom, ws.IncomingJoinRoomData{RoomId: roomID}); err != nil {
+			c.screen.Println("! %v", err)
+			break
+		}
+		c.switchRoom(roomID)
+	case "/room":
+		roomID := c.resolveRoom(rest)
+		if roomID == "" {
+			c.screen.Println("! usage: /room <room|#n>")
+			break
+		}
+		c.switchRoom(roomID)
+	case "/reply":
+		ref, text, _ := strings.Cut(rest, " ")
+		msg, ok := c.messageRef(ref)
+		if !ok || strings.TrimSpace(text) == "" {
+			c.screen.Println("! usage: /reply <#n> <text>")
+			break
+		}
+		c.sendText(strings.TrimSpace(text), &msg.content)
+	case "/react":
+		ref, reaction, _ := strings.Cut(rest, " ")
+		msg, ok := c.messageRef(ref)
+		reactType, known := reactionNames[strings.TrimSpace(reaction)]
+		if !ok || !known {
+			c.screen.Println("! usage: /react <#n> like|dislike|love")
+			break
+		}
+		if err := c.client.send(ws.TypeReactMessage, ws.IncomingReactData{MessageId: msg.id, ReactType: reactType}); err != nil {
+			c.screen.Println("! %v", err)
+		}
+	default:
+		c.screen.Println("! unknown command %s, try /help", name)
+	}
+	return true
+}
+
+func (c *chat) sendText(text string, replyContent *string) {
+	c.mu.Lock()
+	roomID := c.current
+	c.mu.Unlock()
+	if roomID == "" {
+		c.screen.Println("! no current room, use /join or /room first")
+		return
+	}
+	err := c.client.send(ws.TypeTextMessage, ws.IncomingTextData{
+		Content:      text,
+		RoomId:       roomID,
+		ReplyContent: replyContent,
+	})
+	if err != nil {
+		c.screen.Println("! %v", err)
+	}
+}
+
+func (c *chat) listRooms(rooms []publicRoom) {
+	c.rememberRooms(rooms)
+
+	c.mu.Lock()
+	c.listed = c.listed[:0]
+	for _, r := range rooms {
+		c.listed = append(c.listed, r.ID)
+	}
+	c.mu.Unlock()
+
+	if len(rooms) == 0 {
+		c.screen.Println("* no rooms")
+		return
+	}
+	for i, r := range rooms {
+		joined := ""
+		if r.IsJoined {
+			joined = " (joined)"
+		}
+		c.screen.Println("#%d %s  %d members, %d online%s  [%s]", i+1, r.RoomName, r.MemberNumber, r.OnlineNumber, joined, r.ID)
+		if r.Topic != "" {
+			c.screen.Println("    %s", r.Topic)
+		}
+	}
+}
+
+func (c *chat) rememberRooms(rooms []publicRoom) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, r := range rooms {
+		c.rooms[r.ID] = r.RoomName
+	}
+}
+
+// resolveRoom accepts a room id or #n from the last listing.
+func (c *chat) resolveRoom(arg string) string {
+	if !strings.HasPrefix(arg, "#") {
+		return arg
+	}
+	n, err := strconv.Atoi(arg[1:])
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil || n < 1 || n > len(c.listed) {
+		return ""
+	}
+	return c.listed[n-1]
+}
+
+func (c *chat) messageRef(arg string) (shownMessage, bool) {
+	n, err := strconv.Atoi(strings.TrimPrefix(arg, "#"))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil || n < 1 || n > len(c.messages) {
+		return shownMessage{}, false
+	}
+	return c.messages[n-1], true
+}
+
+// switchRoom makes the room current and prints its recent history as
+// scrollback.
+func (c *chat) switchRoom(roomID string) {
+	c.mu.Lock()
+	c.current = roomID
+	c.mu.Unlock()
+	c.refreshStatus()
+
+	history, err := c.client.history(roomID)
+	if err != nil {
+		c.screen.Println("! history: %v", err)
+		return
+	}
+	if len(history) > historySize {
+		history = history[len(history)-historySize:]
+	}
+	c.screen.Println("--- %s ---", c.roomName(roomID))
+	for _, m := range history {
+		created, _ := time.Parse(time.RFC3339, m.CreatedAt)
+		c.printMessage(m.ID, roomID, m.SenderName, m.Content, m.ReplyTo, created)
+	}
+}
+
+func (c *chat) roomName(roomID string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if name := c.rooms[roomID]; name != "" {
+		return name
+	}
+	if len(roomID) > 8 {
+		return roomID[len(roomID)-8:]
+	}
+	return roomID
+}
+
+func (c *chat) refreshStatus() {
+	c.mu.Lock()
+	roomID := c.current
+	c.mu.Unlock()
+	if roomID == "" {
+		c.screen.SetStatus("no room - /rooms, /join <room>")
+		return
+	}
+	c.screen.SetStatus("room: " + c.roomName(roomID))
+}
+
+func (c *chat) printMessage(id, roomID, sender, content, replyTo string, at time.Time) {
+	c.mu.Lock()
+	c.messages = append(c.messages, shownMessage{id: id, content: content})
+	n := len(c.messages)
+	current := c.current
+	c.mu.Unlock()
+
+	prefix := ""
+	if roomID != current {
+		prefix = "[" + c.roomName(roomID) + "] "
+	}
+	if replyTo != "" {
+		c.screen.Println("      ↪ %q", replyTo)
+	}
+	c.screen.Println("%s#%d %s %s: %s", prefix, n, at.Local().Format("15:04"), sender, content)
+}
+
+func (c *chat) render(envelope ws.WsMessage) {
+	switch envelope.Type {
+	case ws.TypeTextMessage:
+		var m ws.OutgoingTextData
+		if json.Unmarshal(envelope.Data, &m) != nil {
+			return
+		}
+		reply := ""
+		if m.ReplyContent != nil {
+			reply = *m.ReplyContent
+		}
+		c.printMessage(m.MessageId, m.RoomId, m.SenderName, m.Content, reply, m.CreatedAt)
+
+	case ws.TypeReactMessage:
+		var r ws.OutgoingReactData
+		if json.Unmarshal(envelope.Data, &r) != nil {
+			return
+		}
+		c.screen.Println("* %s reaction on %s", reactionLabel(r.ReactType), c.messageLabel(r.MessageId))
+
+	case ws.TypePresenceSnapshot:
+		var snap ws.PresenceSnapshotData
+		if json.Unmarshal(envelope.Data, &snap) != nil {
+			return
+		}
+		names := make([]string, 0, len(snap.Users))
+		for _, u := range snap.Users {
+			c.rememberUser(u.UserId, u.Name)
+			names = append(names, u.Name)
+		}
+		c.screen.Println("* online: %s", strings.Join(names, ", "))
+
+	case ws.TypeUserPresence:
+		var u ws.UserPresenceData
+		if json.Unmarshal(envelope.Data, &u) != nil {
+			return
+		}
+		c.rememberUser(u.UserId, u.Name)
+		c.screen.Println("* %s is %s%s", c.userName(u.UserId), envelope.Status, statusSuffix(u.StatusText))
+
+	case ws.TypeRoomPresence:
+		var p ws.RoomPresenceData
+		if json.Unmarshal(envelope.Data, &p) != nil {
+			return
+		}
+		c.rememberUser(p.UserId, p.Name)
+		verb := "is here"
+		if envelope.Status == ws.StatusOffline {
+			verb = "left"
+		}
+		c.screen.Println("* [%s] %s %s", c.roomName(p.RoomId), c.userName(p.UserId), verb)
+
+	case ws.TypeJoinRoom:
+		var j ws.RoomMemberJoinedData
+		if json.Unmarshal(envelope.Data, &j) != nil {
+			return
+		}
+		c.rememberUser(j.UserId, j.Name)
+		c.screen.Println("* %s joined %s", j.Name, c.roomName(j.RoomId))
+
+	case ws.TypeCreateRoom:
+		var r ws.OutgoingCreateRoomData
+		if json.Unmarshal(envelope.Data, &r) != nil {
+			return
+		}
+		c.rememberRooms([]publicRoom{{ID: r.RoomId, RoomName: r.ChatName}})
+		c.screen.Println("* new room %s [%s]", r.ChatName, r.RoomId)
+
+	case ws.TypeError:
+		var e ws.ErrorData
+		if json.Unmarshal(envelope.Data, &e) != nil {
+			return
+		}
+		c.screen.Println("! %s: %s", e.Code, e.Message)
+
+	case ws.TypeClose:
+		var d ws.CloseData
+		if json.Unmarshal(envelope.Data, &d) != nil {
+			return
+		}
+		c.screen.Println("! disconnected (%d): %s", d.Code, d.Reason)
+	}
+}
+
+func (c *chat) rememberUser(userID, name string) {
+	if name == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.names[userID] = name
+}
+
+func (c *chat) userName(userID string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if name := c.names[userID]; name != "" {
+		return name
+	}
+	return userID
+}
+
+func (c *chat) messageLabel(messageID string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := len(c.messages) - 1; i >= 0; i-- {
+		if c.messages[i].id == messageID {
+			return fmt.Sprintf("#%d", i+1)
+		}
+	}
+	return "an earlier message"
+}
+
+func reactionLabel(r domain.ReactionType) string {
+	for name, t := range reactionNames {
+		if t == r {
+			return name
+		}
+	}
+	return string(r)
+}
+
+func statusSuffix(text string) string {
+	if text == "" {
+		return ""
+	}
+	return " - " + text
+}
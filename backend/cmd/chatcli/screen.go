@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// screen is a minimal TUI: a scrolling message area, a status bar and an
+// input line at the bottom, drawn with ANSI escapes. When stdout is not a
+// terminal it degrades to plain line output.
+type screen struct {
+	mu     sync.Mutex
+	out    io.Writer
+	rows   int
+	tty    bool
+	status string
+}
+
+func newScreen() *screen {
+	rows, ok := terminalRows(os.Stdout)
+	s := &screen{out: os.Stdout, rows: rows, tty: ok && rows > 4}
+	if s.tty {
+		// Clear, then confine scrolling to everything above the status bar.
+		fmt.Fprintf(s.out, "\x1b[2J\x1b[1;%dr", s.rows-2)
+		s.drawPrompt()
+	}
+	return s
+}
+
+// Println appends a line to the message area without disturbing the input
+// line the user is typing on.
+func (s *screen) Println(format string, args ...any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	line := fmt.Sprintf(format, args...)
+	if !s.tty {
+		fmt.Fprintln(s.out, line)
+		return
+	}
+	fmt.Fprintf(s.out, "\x1b7\x1b[%d;1H\n%s\x1b8", s.rows-2, line)
+}
+
+func (s *screen) SetStatus(status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.status = status
+	if !s.tty {
+		return
+	}
+	fmt.Fprintf(s.out, "\x1b7\x1b[%d;1H\x1b[2K\x1b[7m %s \x1b[0m\x1b8", s.rows-1, s.status)
+}
+
+// Prompt clears the input line after the user submitted it.
+func (s *screen) Prompt() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tty {
+		s.drawPrompt()
+	}
+}
+
+func (s *screen) drawPrompt() {
+	fmt.Fprintf(s.out, "\x1b[%d;1H\x1b[2K> ", s.rows)
+}
+
+// Close restores the terminal's scrolling region.
+func (s *screen) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tty {
+		fmt.Fprintf(s.out, "\x1b[r\x1b[%d;1H\n", s.rows)
+	}
+}
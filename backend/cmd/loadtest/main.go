@@ -0,0 +1,333 @@
+// Command loadtest measures how the Hub copes with many concurrent clients.
+// It connects N simulated WebSocket clients, has each join M rooms and send
+// messages at a fixed rate, and reports throughput, fan-out latency
+// percentiles and dropped frames.
+//
+// Without -url it starts an in-process server (real Hub, test-mode auth, no
+// database) so the Hub is measured on its own:
+//
+//	go run ./cmd/loadtest -clients 500 -rooms 20 -join 3 -rate 2 -duration 30s
+//
+// Against a running server the rooms must already exist:
+//
+//	go run ./cmd/loadtest -url ws://localhost:8080/ws -test -room-ids id1,id2
+//
+// With -test or -token every client logs in as the same user, so the
+// server's per-user rate limits apply to the whole run. Start the server
+// with WS_RATE_LIMIT=false to measure the Hub rather than the limiter;
+// otherwise rate_limited errors and 4029 disconnects are reported
+// separately.
+//
+// -codec msgpack negotiates binary MessagePack framing instead of JSON.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fasthttp/websocket"
+
+	ws "github.com/napat2224/socket-programming-chat-app/internal/services/websocket"
+)
+
+const contentPrefix = "lt|"
+
+type options struct {
+	url      string
+	token    string
+	test     bool
+	clients  int
+	rooms    int
+	roomIDs  []string
+	join     int
+	rate     float64
+	size     int
+	duration time.Duration
+	drain    time.Duration
+	codec    string
+	verbose  bool
+}
+
+func main() {
+	var o options
+	var roomIDs string
+	flag.StringVar(&o.url, "url", "", "WebSocket URL of a running server; empty starts one in-process")
+	flag.StringVar(&o.token, "token", os.Getenv("CHAT_TOKEN"), "Firebase ID token for -url (defaults to $CHAT_TOKEN)")
+	flag.BoolVar(&o.test, "test", false, "use the server's test-mode login with -url")
+	flag.IntVar(&o.clients, "clients", 100, "number of simulated clients")
+	flag.IntVar(&o.rooms, "rooms", 10, "number of rooms for the in-process server")
+	flag.StringVar(&roomIDs, "room-ids", "", "comma-separated existing room ids for -url")
+	flag.IntVar(&o.join, "join", 2, "rooms each client joins")
+	flag.Float64Var(&o.rate, "rate", 1, "messages per second per client")
+	flag.IntVar(&o.size, "size", 64, "approximate message size in bytes")
+	flag.DurationVar(&o.duration, "duration", 10*time.Second, "how long clients send")
+	flag.DurationVar(&o.drain, "drain", 2*time.Second, "how long to wait for in-flight frames after sending stops")
+	flag.StringVar(&o.codec, "codec", "json", "wire codec to negotiate: json or msgpack")
+	flag.BoolVar(&o.verbose, "v", false, "keep server and client logs")
+	flag.Parse()
+
+	if o.codec != "json" && o.codec != "msgpack" {
+		fmt.Fprintln(os.Stderr, "loadtest: -codec must be json or msgpack")
+		os.Exit(2)
+	}
+	if roomIDs != "" {
+		o.roomIDs = strings.Split(roomIDs, ",")
+	}
+	if !o.verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	if o.url == "" {
+		srv, err := startInProcessServer()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "loadtest:", err)
+			os.Exit(1)
+		}
+		defer srv.Close()
+		o.url = srv.URL()
+		o.roomIDs = make([]string, o.rooms)
+		for i := range o.roomIDs {
+			o.roomIDs[i] = "room-" + strconv.Itoa(i)
+		}
+	} else if len(o.roomIDs) == 0 {
+		fmt.Fprintln(os.Stderr, "loadtest: -room-ids is required with -url")
+		os.Exit(2)
+	} else if o.token == "" && !o.test {
+		fmt.Fprintln(os.Stderr, "loadtest: -token or -test is required with -url")
+		os.Exit(2)
+	}
+	if o.join > len(o.roomIDs) {
+		o.join = len(o.roomIDs)
+	}
+
+	if err := run(o); err != nil {
+		fmt.Fprintln(os.Stderr, "loadtest:", err)
+		os.Exit(1)
+	}
+}
+
+// assign spreads clients over rooms so each room gets a similar share.
+func assign(o options) ([][]string, map[string]int64) {
+	subscribers := map[string]int64{}
+	rooms := make([][]string, o.clients)
+	for i := range rooms {
+		for k := 0; k < o.join; k++ {
+			roomID := o.roomIDs[(i*o.join+k)%len(o.roomIDs)]
+			rooms[i] = append(rooms[i], roomID)
+			subscribers[roomID]++
+		}
+	}
+	return rooms, subscribers
+}
+
+func run(o options) error {
+	rooms, subscribers := assign(o)
+
+	fmt.Printf("connecting %d clients to %s\n", o.clients, o.url)
+	clients := make([]*simClient, 0, o.clients)
+	for i := 0; i < o.clients; i++ {
+		c, err := dialClient(o, i, rooms[i])
+		if err != nil {
+			for _, c := range clients {
+				c.close()
+			}
+			return fmt.Errorf("client %d: %w", i, err)
+		}
+		clients = append(clients, c)
+	}
+
+	// Let every join land before anyone sends, so expected counts hold.
+	time.Sleep(500 * time.Millisecond)
+
+	fmt.Printf("sending for %s\n", o.duration)
+	start := time.Now()
+	stop := make(chan struct{})
+	var senders sync.WaitGroup
+	for _, c := range clients {
+		senders.Add(1)
+		go func(c *simClient) {
+			defer senders.Done()
+			c.sendLoop(o, subscribers, stop)
+		}(c)
+	}
+	time.Sleep(o.duration)
+	close(stop)
+	senders.Wait()
+	elapsed := time.Since(start)
+
+	time.Sleep(o.drain)
+	for _, c := range clients {
+		c.close()
+	}
+
+	stats := make([]*clientStats, 0, len(clients))
+	var expected int64
+	for _, c := range clients {
+		<-c.done
+		stats = append(stats, &c.stats)
+		expected += c.expected
+	}
+	buildReport(stats, expected, o.clients, len(o.roomIDs), elapsed).print(os.Stdout)
+	return nil
+}
+
+type simClient struct {
+	conn    *websocket.Conn
+	codec   ws.Codec
+	rooms   []string
+	joined  map[string]struct{}
+	writeMu sync.Mutex
+	done    chan struct{}
+
+	stats    clientStats
+	expected int64
+}
+
+func dialClient(o options, i int, rooms []string) (*simClient, error) {
+	u, err := url.Parse(o.url)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	switch {
+	case o.test:
+		q.Set("mode", "test")
+	case o.token != "":
+		q.Set("token", o.token)
+	default:
+		q.Set("user", "loadtest-"+strconv.Itoa(i))
+	}
+	q.Set("device", "loadtest")
+	u.RawQuery = q.Encode()
+
+	dialer := *websocket.DefaultDialer
+	dialer.Subprotocols = []string{"chat.v1." + o.codec}
+	conn, _, err := dialer.Dial(u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &simClient{
+		conn:   conn,
+		codec:  ws.CodecForSubprotocol(conn.Subprotocol()),
+		rooms:  rooms,
+		joined: map[string]struct{}{},
+		done:   make(chan struct{}),
+	}
+	for _, roomID := range rooms {
+		c.joined[roomID] = struct{}{}
+		if err := c.send(ws.TypeJoinRoom, ws.IncomingJoinRoomData{RoomId: roomID}); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	go c.readLoop()
+	return c, nil
+}
+
+func (c *simClient) send(t ws.MessageType, data any) error {
+	frame, err := c.codec.Encode(ws.MustMarshal(ws.WsMessage{Type: t, Data: ws.MustMarshal(data)}))
+	if err != nil {
+		return err
+	}
+	kind := websocket.TextMessage
+	if c.codec.Binary() {
+		kind = websocket.BinaryMessage
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.conn.WriteMessage(kind, frame)
+}
+
+func (c *simClient) sendLoop(o options, subscribers map[string]int64, stop <-chan struct{}) {
+	if o.rate <= 0 || len(c.rooms) == 0 {
+		return
+	}
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / o.rate))
+	defer ticker.Stop()
+
+	padding := ""
+	if o.size > 32 {
+		padding = strings.Repeat("x", o.size-32)
+	}
+	for n := 0; ; n++ {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		roomID := c.rooms[n%len(c.rooms)]
+		content := contentPrefix + strconv.FormatInt(time.Now().UnixNano(), 10) + "|" + padding
+		if err := c.send(ws.TypeTextMessage, ws.IncomingTextData{Content: content, RoomId: roomID}); err != nil {
+			c.stats.sendErrs++
+			continue
+		}
+		c.stats.sent++
+		c.expected += subscribers[roomID]
+	}
+}
+
+// readLoop counts frames from rooms this client joined and records how long
+// each took to fan out.
+func (c *simClient) readLoop() {
+	defer close(c.done)
+	for {
+		_, frame, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsCloseError(err, ws.CloseRateLimited) {
+				c.stats.rateLimitClosed = true
+			}
+			return
+		}
+		now := time.Now()
+		raw, err := c.codec.Decode(frame)
+		if err != nil {
+			continue
+		}
+
+		var envelope ws.WsMessage
+		if json.Unmarshal(raw, &envelope) != nil {
+			continue
+		}
+		if envelope.Type == ws.TypeError {
+			var data ws.ErrorData
+			if json.Unmarshal(envelope.Data, &data) == nil && data.Code == ws.ErrCodeRateLimited {
+				c.stats.rateLimited++
+			}
+			continue
+		}
+		if envelope.Type != ws.TypeTextMessage {
+			continue
+		}
+		var msg ws.OutgoingTextData
+		if json.Unmarshal(envelope.Data, &msg) != nil || !strings.HasPrefix(msg.Content, contentPrefix) {
+			continue
+		}
+		if _, ok := c.joined[msg.RoomId]; !ok {
+			continue
+		}
+		stamp, _, _ := strings.Cut(strings.TrimPrefix(msg.Content, contentPrefix), "|")
+		sentAt, err := strconv.ParseInt(stamp, 10, 64)
+		if err != nil {
+			continue
+		}
+		c.stats.received++
+		c.stats.latencies = append(c.stats.latencies, now.Sub(time.Unix(0, sentAt)))
+	}
+}
+
+func (c *simClient) close() {
+	c.writeMu.Lock()
+	_ = c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	c.writeMu.Unlock()
+	_ = c.conn.Close()
+}
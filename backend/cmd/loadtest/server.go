@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+
+	"github.com/napat2224/socket-programming-chat-app/internal/domain"
+	ws "github.com/napat2224/socket-programming-chat-app/internal/services/websocket"
+)
+
+// inProcessServer runs a real Hub behind a real /ws endpoint but without
+// Mongo or Firebase: every client is authenticated test-mode style from the
+// "user" query parameter, join_room only subscribes, and messages are
+// broadcast without being stored. That isolates the Hub and its lock.
+type inProcessServer struct {
+	app *fiber.App
+	ln  net.Listener
+	hub *ws.Hub
+	ids atomic.Int64
+}
+
+func startInProcessServer() (*inProcessServer, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &inProcessServer{
+		app: fiber.New(fiber.Config{DisableStartupMessage: true}),
+		ln:  ln,
+		hub: ws.NewHub(),
+	}
+	s.app.Get("/ws", websocket.New(s.handle, websocket.Config{Subprotocols: ws.Subprotocols}))
+	go func() { _ = s.app.Listener(ln) }()
+	return s, nil
+}
+
+func (s *inProcessServer) URL() string {
+	return "ws://" + s.ln.Addr().String() + "/ws"
+}
+
+func (s *inProcessServer) Close() error {
+	return s.app.Shutdown()
+}
+
+func (s *inProcessServer) handle(c *websocket.Conn) {
+	userId := c.Query("user", "test")
+	conn := ws.NewConnection(c)
+	info := ws.UserPresenceData{UserId: userId, Name: userId, Profile: domain.Profile1}
+	s.hub.AddUser(info, conn)
+	defer func() {
+		s.hub.Remove(conn)
+		conn.Close()
+	}()
+
+	for {
+		raw, err := conn.Read()
+		if err != nil {
+			return
+		}
+		var envelope ws.WsMessage
+		if err := json.Unmarshal(raw, &envelope); err != nil {
+			continue
+		}
+
+		switch envelope.Type {
+		case ws.TypeJoinRoom:
+			var in ws.IncomingJoinRoomData
+			if json.Unmarshal(envelope.Data, &in) == nil {
+				s.hub.AddToRoom(in.RoomId, conn)
+			}
+
+		case ws.TypeTextMessage:
+			var in ws.IncomingTextData
+			if json.Unmarshal(envelope.Data, &in) != nil {
+				continue
+			}
+			out := ws.OutgoingTextData{
+				MessageId:  strconv.FormatInt(s.ids.Add(1), 10),
+				SenderId:   userId,
+				Content:    in.Content,
+				RoomId:     in.RoomId,
+				SenderName: info.Name,
+				CreatedAt:  time.Now(),
+			}
+			s.hub.BroadcastToRoom(in.RoomId, ws.MustMarshal(ws.WsMessage{
+				Type: ws.TypeTextMessage,
+				Data: ws.MustMarshal(out),
+			}))
+		}
+	}
+}
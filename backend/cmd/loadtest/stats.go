@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// clientStats is owned by one simulated client until the run ends, so it
+// needs no locking.
+type clientStats struct {
+	sent            int64
+	sendErrs        int64
+	received        int64
+	rateLimited     int64
+	rateLimitClosed bool
+	latencies       []time.Duration
+}
+
+type report struct {
+	clients   int
+	rooms     int
+	elapsed   time.Duration
+	sent      int64
+	sendErrs  int64
+	expected  int64
+	received  int64
+	latencies []time.Duration
+
+	rateLimited     int64
+	rateLimitClosed int
+}
+
+func buildReport(stats []*clientStats, expected int64, clients, rooms int, elapsed time.Duration) report {
+	r := report{clients: clients, rooms: rooms, elapsed: elapsed, expected: expected}
+	for _, s := range stats {
+		r.sent += s.sent
+		r.sendErrs += s.sendErrs
+		r.received += s.received
+		r.rateLimited += s.rateLimited
+		if s.rateLimitClosed {
+			r.rateLimitClosed++
+		}
+		r.latencies = append(r.latencies, s.latencies...)
+	}
+	sort.Slice(r.latencies, func(i, j int) bool { return r.latencies[i] < r.latencies[j] })
+	return r
+}
+
+func (r report) percentile(p float64) time.Duration {
+	if len(r.latencies) == 0 {
+		return 0
+	}
+	i := int(p / 100 * float64(len(r.latencies)-1))
+	return r.latencies[i].Round(time.Microsecond)
+}
+
+func (r report) print(w io.Writer) {
+	secs := r.elapsed.Seconds()
+	dropped := r.expected - r.received
+	if dropped < 0 {
+		dropped = 0
+	}
+	dropPct := 0.0
+	if r.expected > 0 {
+		dropPct = float64(dropped) / float64(r.expected) * 100
+	}
+
+	fmt.Fprintf(w, "clients %d, rooms %d, duration %s\n", r.clients, r.rooms, r.elapsed.Round(time.Millisecond))
+	fmt.Fprintf(w, "sent      %d (%.1f msg/s), send errors %d\n", r.sent, float64(r.sent)/secs, r.sendErrs)
+	fmt.Fprintf(w, "delivered %d of %d expected (%.1f frames/s)\n", r.received, r.expected, float64(r.received)/secs)
+	fmt.Fprintf(w, "dropped   %d (%.2f%%)\n", dropped, dropPct)
+	if r.rateLimited > 0 || r.rateLimitClosed > 0 {
+		fmt.Fprintf(w, "rate limited %d requests, %d clients disconnected (4029); dropped includes their fan-out, run the server with WS_RATE_LIMIT=false to measure the Hub alone\n",
+			r.rateLimited, r.rateLimitClosed)
+	}
+	fmt.Fprintf(w, "fan-out latency p50 %s  p90 %s  p99 %s  max %s\n",
+		r.percentile(50), r.percentile(90), r.percentile(99), r.percentile(100))
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -100,18 +101,45 @@ func main() {
 	userRepo := repository.NewUserRepository(app.database, cfg.UserCollectionName)
 	roomRepo := repository.NewMongoRoomRepository(app.database, cfg.RoomCollectionName)
 	messageRepo := repository.NewMongoMessageRepository(app.database, cfg.MassageCollectionName)
+	inviteRepo := repository.NewMongoInviteRepository(app.database, cfg.InviteCollectionName)
+	joinRequestRepo := repository.NewMongoJoinRequestRepository(app.database, cfg.JoinRequestCollectionName)
+	preferenceRepo := repository.NewMongoRoomPreferenceRepository(app.database, cfg.PreferenceCollectionName)
+	auditRepo := repository.NewMongoAuditRepository(app.database, cfg.AuditCollectionName)
 
 	// Initialize service here
 	authClient := services.InitFirebase(context.Background(), env.GetString("FIREBASE_SERVICE_ACCOUNT_ENV", ""), cfg.FirebaseAccountKeyFile)
 	authService := services.NewFirebaseAuth(authClient)
 	userService := services.NewUserService(authService, userRepo)
-	userHandler := handlers.NewUserHandler(userService)
 
-	chat := services.NewChatService(roomRepo, messageRepo, userRepo)
+	chat := services.NewChatService(roomRepo, messageRepo, userRepo, joinRequestRepo, inviteRepo, preferenceRepo, auditRepo)
 	hub := ws.NewHub()
-	wsHandler := handlers.NewWsHandler(hub, chat)
+	userHandler := handlers.NewUserHandler(userService, chat, hub)
+	rateLimits, err := ws.ParseRateLimits(ws.DefaultRateLimits(), cfg.WSRateLimits)
+	if err != nil {
+		log.Fatalf("Invalid WS_RATE_LIMITS: %v", err)
+	}
+	rateLimits.Enabled = cfg.WSRateLimit
+	rateLimits.Violations = ws.Rate{PerSecond: float64(cfg.WSRateLimitStrikes) / 60, Burst: cfg.WSRateLimitStrikes}
+	rateLimits.Cooldown = time.Duration(cfg.WSRateLimitCooldown) * time.Second
+	wsHandler := handlers.NewWsHandler(hub, chat, userService, ws.Compression{
+		Enabled:   cfg.WSCompression,
+		Level:     cfg.WSCompressionLevel,
+		Threshold: cfg.WSCompressionThreshold,
+	}, rateLimits)
+	go wsHandler.RunIdleMonitor(context.Background())
+	tcpHandler := handlers.NewTCPHandler(wsHandler, authService)
+	go func() {
+		if err := tcpHandler.ListenAndServe(":" + cfg.TCPPort); err != nil {
+			log.Printf("TCP listener stopped: %v", err)
+		}
+	}()
 	authMid := middleware.NewAuthMiddleware(authService)
-	chatHandler := handlers.NewChatHandler(chat)
+	chatHandler := handlers.NewChatHandler(chat, hub)
+	moderationHandler := handlers.NewModerationHandler(chat, hub)
+	inviteService := services.NewInviteService(inviteRepo, roomRepo, auditRepo)
+	inviteHandler := handlers.NewInviteHandler(inviteService, hub)
+	joinRequestHandler := handlers.NewJoinRequestHandler(chat, hub)
+	sseHandler := handlers.NewSSEHandler(wsHandler, hub)
 	// ws hub
 	router.SetupRoutes(
 		app.app,
@@ -119,6 +147,10 @@ func main() {
 		userHandler,
 		wsHandler,
 		chatHandler,
+		moderationHandler,
+		inviteHandler,
+		joinRequestHandler,
+		sseHandler,
 	)
 
 	// Graceful shutdown
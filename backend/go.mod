@@ -16,7 +16,9 @@ require go.mongodb.org/mongo-driver v1.17.4
 
 require (
 	firebase.google.com/go/v4 v4.18.0
+	github.com/fasthttp/websocket v1.5.3
 	github.com/gofiber/websocket/v2 v2.2.1
+	golang.org/x/sys v0.37.0
 	google.golang.org/api v0.231.0
 	google.golang.org/grpc v1.76.0
 )
@@ -41,7 +43,6 @@ require (
 	github.com/cncf/xds/go v0.0.0-20250501225837-2ac532fd4443 // indirect
 	github.com/envoyproxy/go-control-plane/envoy v1.32.4 // indirect
 	github.com/envoyproxy/protoc-gen-validate v1.2.1 // indirect
-	github.com/fasthttp/websocket v1.5.3 // indirect
 	github.com/felixge/httpsnoop v1.0.4 // indirect
 	github.com/go-jose/go-jose/v4 v4.1.2 // indirect
 	github.com/go-logr/logr v1.4.3 // indirect
@@ -86,7 +87,6 @@ require (
 	golang.org/x/net v0.45.0 // indirect
 	golang.org/x/oauth2 v0.30.0 // indirect
 	golang.org/x/sync v0.17.0 // indirect
-	golang.org/x/sys v0.37.0 // indirect
 	golang.org/x/text v0.30.0 // indirect
 	golang.org/x/time v0.11.0 // indirect
 	google.golang.org/appengine/v2 v2.0.6 // indirect
@@ -0,0 +1,34 @@
+package domain
+
+import "time"
+
+type AuditAction string
+
+const (
+	AuditRoomUpdated    AuditAction = "room_updated"
+	AuditRoomArchived   AuditAction = "room_archived"
+	AuditRoomUnarchived AuditAction = "room_unarchived"
+	AuditMemberKicked   AuditAction = "member_kicked"
+	AuditMemberBanned   AuditAction = "member_banned"
+	AuditMemberUnbanned AuditAction = "member_unbanned"
+	AuditMemberMuted    AuditAction = "member_muted"
+	AuditMemberUnmuted  AuditAction = "member_unmuted"
+	AuditRoleChanged    AuditAction = "role_changed"
+	AuditJoinApproved   AuditAction = "join_request_approved"
+	AuditJoinRejected   AuditAction = "join_request_rejected"
+	AuditInviteCreated  AuditAction = "invite_created"
+	AuditInviteRevoked  AuditAction = "invite_revoked"
+)
+
+// AuditEntry records one administrative change to a room. Before and After
+// hold only the fields the action touched.
+type AuditEntry struct {
+	ID        string         `json:"id"`
+	RoomID    string         `json:"roomId"`
+	ActorID   string         `json:"actorId"`
+	Action    AuditAction    `json:"action"`
+	TargetID  string         `json:"targetId,omitempty"`
+	Before    map[string]any `json:"before,omitempty"`
+	After     map[string]any `json:"after,omitempty"`
+	CreatedAt time.Time      `json:"createdAt"`
+}
@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+type RoomInvite struct {
+	Token     string     `json:"token"`
+	RoomID    string     `json:"roomId"`
+	CreatedBy string     `json:"createdBy"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	MaxUses   int        `json:"maxUses,omitempty"` // 0 means unlimited
+	Uses      int        `json:"uses"`
+	Revoked   bool       `json:"revoked"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// Usable reports whether the invite can still be redeemed at now.
+func (i *RoomInvite) Usable(now time.Time) bool {
+	if i.Revoked {
+		return false
+	}
+	if i.ExpiresAt != nil && !i.ExpiresAt.After(now) {
+		return false
+	}
+	return i.MaxUses == 0 || i.Uses < i.MaxUses
+}
@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+type JoinRequestStatus string
+
+const (
+	JoinRequestPending  JoinRequestStatus = "pending"
+	JoinRequestApproved JoinRequestStatus = "approved"
+	JoinRequestRejected JoinRequestStatus = "rejected"
+)
+
+type JoinRequest struct {
+	ID        string            `json:"id"`
+	RoomID    string            `json:"roomId"`
+	UserID    string            `json:"userId"`
+	Status    JoinRequestStatus `json:"status"`
+	DecidedBy string            `json:"decidedBy,omitempty"`
+	CreatedAt time.Time         `json:"createdAt"`
+	DecidedAt *time.Time        `json:"decidedAt,omitempty"`
+}
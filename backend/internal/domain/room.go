@@ -5,13 +5,37 @@ import (
 )
 
 type Room struct {
-	ID              string          `json:"id"`
-	CreatorID       string          `json:"creatorId"`
-	MemberIDs       []string        `json:"memberIds"`
-	RoomName        string          `json:"roomName,omitempty"`
-	BackgroundColor BackgroundColor `json:"backgroundColor,omitempty"`
-	LastMessageSent time.Time       `json:"lastMessageSent,omitempty"`
-	IsPublic        bool            `json:"isPublic"`
+	ID               string               `json:"id"`
+	CreatorID        string               `json:"creatorId"`
+	MemberIDs        []string             `json:"memberIds"`
+	AdminIDs         []string             `json:"adminIds,omitempty"`
+	BannedIDs        []string             `json:"bannedIds,omitempty"`
+	MutedUntil       map[string]time.Time `json:"mutedUntil,omitempty"`
+	RoomName         string               `json:"roomName,omitempty"`
+	Topic            string               `json:"topic,omitempty"`
+	Description      string               `json:"description,omitempty"`
+	AvatarURL        string               `json:"avatarUrl,omitempty"`
+	Tags             []string             `json:"tags,omitempty"`
+	BackgroundColor  BackgroundColor      `json:"backgroundColor,omitempty"`
+	LastMessageSent  time.Time            `json:"lastMessageSent,omitempty"`
+	IsPublic         bool                 `json:"isPublic"`
+	RequiresApproval bool                 `json:"requiresApproval,omitempty"`
+	ArchivedAt       *time.Time           `json:"archivedAt,omitempty"`
+	AnnouncementOnly bool                 `json:"announcementOnly,omitempty"`
+	SlowModeSeconds  int                  `json:"slowModeSeconds,omitempty"`
+	CreatedAt        time.Time            `json:"createdAt"`
+}
+
+type RoomVisibility string
+
+const (
+	VisibilityPublic     RoomVisibility = "public"
+	VisibilityRestricted RoomVisibility = "restricted"
+	VisibilityPrivate    RoomVisibility = "private"
+)
+
+func (v RoomVisibility) IsValid() bool {
+	return v == VisibilityPublic || v == VisibilityRestricted || v == VisibilityPrivate
 }
 
 type BackgroundColor string
@@ -25,6 +49,35 @@ const (
 	ColorPink   BackgroundColor = "6"
 )
 
+func (c BackgroundColor) IsValid() bool {
+	return c >= ColorRed && c <= ColorPink && len(c) == 1
+}
+
+// RoomSettings is a partial update of a room's metadata. Nil fields are left
+// unchanged.
+type RoomSettings struct {
+	RoomName        *string          `json:"roomName,omitempty"`
+	Topic           *string          `json:"topic,omitempty"`
+	Description     *string          `json:"description,omitempty"`
+	AvatarURL       *string          `json:"avatarUrl,omitempty"`
+	BackgroundColor *BackgroundColor `json:"backgroundColor,omitempty"`
+	Tags            *[]string        `json:"tags,omitempty"`
+	// AnnouncementOnly limits posting to admins; SlowModeSeconds is the
+	// minimum gap between two messages of the same member (0 disables it).
+	AnnouncementOnly *bool `json:"announcementOnly,omitempty"`
+	SlowModeSeconds  *int  `json:"slowModeSeconds,omitempty"`
+}
+
+// OnlyTheme reports whether the update touches nothing but the background.
+func (s RoomSettings) OnlyTheme() bool {
+	return s.RoomName == nil && s.Topic == nil && s.Description == nil && s.AvatarURL == nil && s.Tags == nil &&
+		s.AnnouncementOnly == nil && s.SlowModeSeconds == nil
+}
+
+func (s RoomSettings) IsEmpty() bool {
+	return s.OnlyTheme() && s.BackgroundColor == nil
+}
+
 func CreateRoom(id, creatorID, roomName, backgroundColor string, memberIDs []string, lastMessageSent time.Time, isPublic bool) *Room {
 	return &Room{
 		ID:              id,
@@ -36,3 +89,86 @@ func CreateRoom(id, creatorID, roomName, backgroundColor string, memberIDs []str
 		IsPublic:        isPublic,
 	}
 }
+
+// Visibility derives the join mode. Restricted rooms are listed like public
+// ones, but joining goes through an admin-approved join request.
+func (r *Room) Visibility() RoomVisibility {
+	switch {
+	case !r.IsPublic:
+		return VisibilityPrivate
+	case r.RequiresApproval:
+		return VisibilityRestricted
+	default:
+		return VisibilityPublic
+	}
+}
+
+// Admins returns the creator followed by the promoted admins.
+func (r *Room) Admins() []string {
+	admins := []string{r.CreatorID}
+	for _, id := range r.AdminIDs {
+		if id != r.CreatorID {
+			admins = append(admins, id)
+		}
+	}
+	return admins
+}
+
+// IsArchived reports whether the room is read-only.
+func (r *Room) IsArchived() bool {
+	return r.ArchivedAt != nil
+}
+
+func (r *Room) IsMember(userID string) bool {
+	return contains(r.MemberIDs, userID)
+}
+
+// IsAdmin reports whether the user may moderate the room. The creator is
+// always an admin even if not listed in AdminIDs.
+func (r *Room) IsAdmin(userID string) bool {
+	return userID != "" && (r.CreatorID == userID || contains(r.AdminIDs, userID))
+}
+
+func (r *Room) IsBanned(userID string) bool {
+	return contains(r.BannedIDs, userID)
+}
+
+// MutedAt returns the mute expiry for the user if they are still muted at now.
+func (r *Room) MutedAt(userID string, now time.Time) (time.Time, bool) {
+	until, ok := r.MutedUntil[userID]
+	if !ok || !until.After(now) {
+		return time.Time{}, false
+	}
+	return until, true
+}
+
+func contains(ids []string, id string) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+type RoomSort string
+
+const (
+	RoomSortCreated  RoomSort = "created"
+	RoomSortMembers  RoomSort = "members"
+	RoomSortActivity RoomSort = "activity"
+)
+
+func (s RoomSort) IsValid() bool {
+	return s == RoomSortCreated || s == RoomSortMembers || s == RoomSortActivity
+}
+
+// RoomDirectoryQuery selects a page of the public room directory. Cursor is
+// the opaque value returned with the previous page.
+type RoomDirectoryQuery struct {
+	Search string
+	Tags   []string
+	Sort   RoomSort
+	Limit  int
+	Cursor string
+}
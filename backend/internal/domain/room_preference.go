@@ -0,0 +1,28 @@
+package domain
+
+import "time"
+
+// RoomPreference holds one user's personal settings for a room.
+type RoomPreference struct {
+	UserID                  string     `json:"userId"`
+	RoomID                  string     `json:"roomId"`
+	NotificationsMutedUntil *time.Time `json:"notificationsMutedUntil,omitempty"`
+	Favourite               bool       `json:"favourite"`
+	Hidden                  bool       `json:"hidden"`
+	Nickname                string     `json:"nickname,omitempty"`
+	UpdatedAt               time.Time  `json:"updatedAt"`
+}
+
+// NotificationsMuted reports whether notifications are muted at now.
+func (p *RoomPreference) NotificationsMuted(now time.Time) bool {
+	return p != nil && p.NotificationsMutedUntil != nil && p.NotificationsMutedUntil.After(now)
+}
+
+// RoomPreferenceUpdate is a partial update of a RoomPreference. MuteSeconds
+// mutes notifications for that long; 0 unmutes.
+type RoomPreferenceUpdate struct {
+	MuteSeconds *int    `json:"muteSeconds,omitempty"`
+	Favourite   *bool   `json:"favourite,omitempty"`
+	Hidden      *bool   `json:"hidden,omitempty"`
+	Nickname    *string `json:"nickname,omitempty"`
+}
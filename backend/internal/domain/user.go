@@ -1,5 +1,7 @@
 package domain
 
+import "time"
+
 type ProfileType int
 
 const (
@@ -13,9 +15,68 @@ func (p ProfileType) IsValid() bool {
 	return p >= Profile1 && p <= Profile4
 }
 
+// PresenceStatus is the status a user picks for themselves. Invisible is
+// shorthand for AppearOffline and is never stored as a status.
+type PresenceStatus string
+
+const (
+	PresenceOnline       PresenceStatus = "online"
+	PresenceAway         PresenceStatus = "away"
+	PresenceBusy         PresenceStatus = "busy"
+	PresenceDoNotDisturb PresenceStatus = "dnd"
+	PresenceInvisible    PresenceStatus = "invisible"
+)
+
+func (s PresenceStatus) IsValid() bool {
+	switch s {
+	case PresenceOnline, PresenceAway, PresenceBusy, PresenceDoNotDisturb, PresenceInvisible:
+		return true
+	}
+	return false
+}
+
 type User struct {
 	UserID  string      `json:"userId"`
 	Email   string      `json:"email"`
 	Name    string      `json:"name"`
 	Profile ProfileType `json:"profile"`
+
+	// AppearOffline hides the user's presence from everyone else.
+	AppearOffline bool `json:"appearOffline"`
+
+	Status          PresenceStatus `json:"status,omitempty"`
+	StatusText      string         `json:"statusText,omitempty"`
+	StatusExpiresAt *time.Time     `json:"statusExpiresAt,omitempty"`
+
+	// LastSeenAt is refreshed while the user is visibly online and when
+	// their last connection drops. HideLastSeen keeps it from others.
+	LastSeenAt   *time.Time `json:"lastSeenAt,omitempty"`
+	HideLastSeen bool       `json:"hideLastSeen"`
+}
+
+// VisibleLastSeen returns the last-seen time other users may see.
+func (u *User) VisibleLastSeen() *time.Time {
+	if u == nil || u.HideLastSeen {
+		return nil
+	}
+	return u.LastSeenAt
+}
+
+// CurrentStatus returns the user's chosen status and text at now, falling
+// back to online once the status has expired.
+func (u *User) CurrentStatus(now time.Time) (PresenceStatus, string, *time.Time) {
+	if u == nil || u.Status == "" || (u.StatusExpiresAt != nil && !u.StatusExpiresAt.After(now)) {
+		return PresenceOnline, "", nil
+	}
+	return u.Status, u.StatusText, u.StatusExpiresAt
+}
+
+// PresenceUpdate is a partial update of a user's presence settings.
+// ExpiresInSeconds applies to Status and StatusText; 0 means no expiry.
+type PresenceUpdate struct {
+	AppearOffline    *bool           `json:"appearOffline,omitempty"`
+	Status           *PresenceStatus `json:"status,omitempty"`
+	StatusText       *string         `json:"statusText,omitempty"`
+	ExpiresInSeconds *int            `json:"expiresInSeconds,omitempty"`
+	HideLastSeen     *bool           `json:"hideLastSeen,omitempty"`
 }
@@ -2,43 +2,101 @@ package handlers
 
 import (
 	"context"
+	"log"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/napat2224/socket-programming-chat-app/internal/domain"
 	"github.com/napat2224/socket-programming-chat-app/internal/services"
+	ws "github.com/napat2224/socket-programming-chat-app/internal/services/websocket"
 )
 
 type ChatHandler struct {
 	chatService *services.ChatService
+	hub         *ws.Hub
 }
 
-func NewChatHandler(service *services.ChatService) *ChatHandler {
+func NewChatHandler(service *services.ChatService, hub *ws.Hub) *ChatHandler {
 	return &ChatHandler{
 		chatService: service,
+		hub:         hub,
 	}
 }
 
 // PublicRoomResponse represents a public room with additional user-specific fields
 type PublicRoomResponse struct {
-	ID              string                 `json:"id"`
-	CreatorID       string                 `json:"creatorId"`
-	RoomName        string                 `json:"roomName,omitempty"`
-	BackgroundColor domain.BackgroundColor `json:"backgroundColor,omitempty"`
-	LastMessageSent time.Time              `json:"lastMessageSent,omitempty"`
-	IsPublic        bool                   `json:"isPublic"`
-	IsJoined        bool                   `json:"isJoined"`
-	MemberNumber    int                    `json:"memberNumber"`
+	ID               string                 `json:"id"`
+	CreatorID        string                 `json:"creatorId"`
+	RoomName         string                 `json:"roomName,omitempty"`
+	Topic            string                 `json:"topic,omitempty"`
+	Tags             []string               `json:"tags,omitempty"`
+	BackgroundColor  domain.BackgroundColor `json:"backgroundColor,omitempty"`
+	LastMessageSent  time.Time              `json:"lastMessageSent,omitempty"`
+	CreatedAt        time.Time              `json:"createdAt"`
+	IsPublic         bool                   `json:"isPublic"`
+	RequiresApproval bool                   `json:"requiresApproval,omitempty"`
+	IsJoined         bool                   `json:"isJoined"`
+	MemberNumber     int                    `json:"memberNumber"`
+	OnlineNumber     int                    `json:"onlineNumber"`
+	RoomPreferenceFields
 }
 
-// Implement handler for each API endpoints here
+// RoomPreferenceFields are the current user's personal settings for a room,
+// merged into every room list entry.
+type RoomPreferenceFields struct {
+	IsFavourite             bool       `json:"isFavourite"`
+	IsHidden                bool       `json:"isHidden"`
+	NotificationsMutedUntil *time.Time `json:"notificationsMutedUntil,omitempty"`
+	Nickname                string     `json:"nickname,omitempty"`
+}
+
+func (h *ChatHandler) roomResponse(room *domain.Room, currentUserID string, pref *domain.RoomPreference) PublicRoomResponse {
+	resp := PublicRoomResponse{
+		ID:               room.ID,
+		CreatorID:        room.CreatorID,
+		RoomName:         room.RoomName,
+		Topic:            room.Topic,
+		Tags:             room.Tags,
+		BackgroundColor:  room.BackgroundColor,
+		LastMessageSent:  room.LastMessageSent,
+		CreatedAt:        room.CreatedAt,
+		IsPublic:         room.IsPublic,
+		RequiresApproval: room.RequiresApproval,
+		IsJoined:         currentUserID != "" && room.IsMember(currentUserID),
+		MemberNumber:     len(room.MemberIDs),
+		OnlineNumber:     h.hub.OnlineCount(room.MemberIDs),
+	}
+	if pref != nil {
+		resp.IsFavourite = pref.Favourite
+		resp.IsHidden = pref.Hidden
+		resp.Nickname = pref.Nickname
+		if pref.NotificationsMuted(time.Now()) {
+			resp.NotificationsMutedUntil = pref.NotificationsMutedUntil
+		}
+	}
+	return resp
+}
+
+// GetPublicRooms serves the public room directory. Query parameters:
+// q (name/topic search), tags (comma separated, all must match),
+// sort (created, members or activity), limit and cursor.
 func (h *ChatHandler) GetPublicRooms(c *fiber.Ctx) error {
 	ctx := context.Background()
-	rooms, err := h.chatService.GetAllPublicRooms(ctx)
+
+	query := domain.RoomDirectoryQuery{
+		Search: c.Query("q"),
+		Sort:   domain.RoomSort(c.Query("sort")),
+		Limit:  c.QueryInt("limit"),
+		Cursor: c.Query("cursor"),
+	}
+	if tags := c.Query("tags"); tags != "" {
+		query.Tags = strings.Split(tags, ",")
+	}
+
+	rooms, next, err := h.chatService.SearchPublicRooms(ctx, query)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return errorResponse(c, err, "failed to get public rooms")
 	}
 
 	// Get current user ID from claims if authenticated
@@ -48,30 +106,44 @@ func (h *ChatHandler) GetPublicRooms(c *fiber.Ctx) error {
 		currentUserID = claims.UserID
 	}
 
+	prefs := map[string]*domain.RoomPreference{}
+	if currentUserID != "" {
+		roomIDs := make([]string, 0, len(rooms))
+		for _, room := range rooms {
+			roomIDs = append(roomIDs, room.ID)
+		}
+		prefs, err = h.chatService.GetRoomPreferences(ctx, currentUserID, roomIDs)
+		if err != nil {
+			return errorResponse(c, err, "failed to get room preferences")
+		}
+	}
+
 	// Transform rooms to response DTOs with isJoined and memberNumber fields
 	response := make([]PublicRoomResponse, 0, len(rooms))
 	for _, room := range rooms {
-		isJoined := false
-		if currentUserID != "" {
-			// Check if current user is in the room's member list
-			for _, memberID := range room.MemberIDs {
-				if memberID == currentUserID {
-					isJoined = true
-					break
-				}
-			}
-		}
+		response = append(response, h.roomResponse(room, currentUserID, prefs[room.ID]))
+	}
 
-		response = append(response, PublicRoomResponse{
-			ID:              room.ID,
-			CreatorID:       room.CreatorID,
-			RoomName:        room.RoomName,
-			BackgroundColor: room.BackgroundColor,
-			LastMessageSent: room.LastMessageSent,
-			IsPublic:        room.IsPublic,
-			IsJoined:        isJoined,
-			MemberNumber:    len(room.MemberIDs),
-		})
+	return c.JSON(fiber.Map{
+		"data":       response,
+		"nextCursor": next,
+	})
+}
+
+// GetMyRooms lists the current user's rooms, favourites first. Hidden rooms
+// are included only with ?includeHidden=true.
+func (h *ChatHandler) GetMyRooms(c *fiber.Ctx) error {
+	ctx := context.Background()
+	claims := c.Locals("claims").(*services.Claims)
+
+	rooms, err := h.chatService.ListUserRooms(ctx, claims.UserID, c.QueryBool("includeHidden"))
+	if err != nil {
+		return errorResponse(c, err, "failed to get rooms")
+	}
+
+	response := make([]PublicRoomResponse, 0, len(rooms))
+	for _, r := range rooms {
+		response = append(response, h.roomResponse(r.Room, claims.UserID, r.Preference))
 	}
 
 	return c.JSON(fiber.Map{
@@ -79,6 +151,41 @@ func (h *ChatHandler) GetPublicRooms(c *fiber.Ctx) error {
 	})
 }
 
+func (h *ChatHandler) GetRoomPreference(c *fiber.Ctx) error {
+	ctx := context.Background()
+	roomID := c.Params("roomID")
+	claims := c.Locals("claims").(*services.Claims)
+
+	prefs, err := h.chatService.GetRoomPreferences(ctx, claims.UserID, []string{roomID})
+	if err != nil {
+		return errorResponse(c, err, "failed to get room preferences")
+	}
+
+	return c.JSON(fiber.Map{
+		"data": prefs[roomID],
+	})
+}
+
+func (h *ChatHandler) UpdateRoomPreference(c *fiber.Ctx) error {
+	ctx := context.Background()
+	roomID := c.Params("roomID")
+	claims := c.Locals("claims").(*services.Claims)
+
+	var body domain.RoomPreferenceUpdate
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	pref, err := h.chatService.UpdateRoomPreference(ctx, claims.UserID, roomID, body)
+	if err != nil {
+		return errorResponse(c, err, "failed to update room preferences")
+	}
+
+	return c.JSON(fiber.Map{
+		"data": pref,
+	})
+}
+
 func (h *ChatHandler) GetPrivateRoomByTargetID(c *fiber.Ctx) error {
 	ctx := context.Background()
 	targetID := c.Params("targetID")
@@ -98,12 +205,19 @@ func (h *ChatHandler) GetPrivateRoomByTargetID(c *fiber.Ctx) error {
 func (h *ChatHandler) GetChatRoomByRoomID(c *fiber.Ctx) error {
 	ctx := context.Background()
 	roomID := c.Params("roomID")
-	room, err := h.chatService.GetChatRoomByRoomID(ctx, roomID)
+	claims := c.Locals("claims").(*services.Claims)
+	room, isMember, err := h.chatService.GetChatRoomByRoomID(ctx, roomID, claims.UserID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to get chat room",
 		})
 	}
+	// Presence is only shown to people who share the room.
+	if isMember {
+		for i := range room.Members {
+			room.Members[i].Online = h.hub.AppearsOnline(room.Members[i].ID)
+		}
+	}
 	return c.JSON(fiber.Map{
 		"data": room,
 	})
@@ -123,32 +237,102 @@ func (h *ChatHandler) GetMessagesByRoomID(c *fiber.Ctx) error {
 	})
 }
 
-func (h *ChatHandler) UpdateBackgroundRoom(c *fiber.Ctx) error {
+// UpdateRoomSettings applies a partial settings update. The body may carry
+// any field of domain.RoomSettings; omitted fields are left unchanged.
+func (h *ChatHandler) UpdateRoomSettings(c *fiber.Ctx) error {
 	ctx := context.Background()
 
 	roomID := c.Params("roomID")
+	claims := c.Locals("claims").(*services.Claims)
 
-	type Body struct {
-		BackgroundColor string `json:"backgroundColor"`
-	}
-
-	var body Body
+	var body domain.RoomSettings
 	if err := c.BodyParser(&body); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
 	}
 
-	resp, err := h.chatService.UpdateBackgroundRoom(ctx, roomID, body.BackgroundColor)
+	room, err := h.chatService.UpdateRoomSettings(ctx, roomID, claims.UserID, body)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "failed to update room background",
-		})
+		return errorResponse(c, err, "failed to update room settings")
+	}
+
+	out := ws.RoomUpdatedData{
+		RoomId:      room.ID,
+		UpdatedBy:   claims.UserID,
+		RoomName:    room.RoomName,
+		Topic:       room.Topic,
+		Description: room.Description,
+		AvatarUrl:   room.AvatarURL,
+		Tags:        room.Tags,
+		Background:  room.BackgroundColor,
+
+		AnnouncementOnly: room.AnnouncementOnly,
+		SlowModeSeconds:  room.SlowModeSeconds,
 	}
+	h.hub.SendToUsers(room.MemberIDs, ws.MustMarshal(ws.WsMessage{
+		Type: ws.TypeRoomUpdated,
+		Data: ws.MustMarshal(out),
+	}))
 
 	return c.JSON(fiber.Map{
-		"backgroundColor": resp.BackgroundColor,
+		"backgroundColor": room.BackgroundColor,
+		"data":            room,
 	})
 }
 
+func (h *ChatHandler) ArchiveRoom(c *fiber.Ctx) error   { return h.setArchived(c, true) }
+func (h *ChatHandler) UnarchiveRoom(c *fiber.Ctx) error { return h.setArchived(c, false) }
+
+func (h *ChatHandler) setArchived(c *fiber.Ctx, archived bool) error {
+	ctx := context.Background()
+
+	roomID := c.Params("roomID")
+	claims := c.Locals("claims").(*services.Claims)
+
+	room, err := h.chatService.SetRoomArchived(ctx, roomID, claims.UserID, archived)
+	if err != nil {
+		return errorResponse(c, err, "failed to archive room")
+	}
+
+	out := ws.RoomArchivedData{
+		RoomId:   room.ID,
+		ActorId:  claims.UserID,
+		Archived: archived,
+	}
+	h.hub.BroadcastToRoomAndUsers(room.ID, room.MemberIDs, ws.MustMarshal(ws.WsMessage{
+		Type: ws.TypeRoomArchived,
+		Data: ws.MustMarshal(out),
+	}))
+
+	return c.JSON(fiber.Map{
+		"data": room,
+	})
+}
+
+func (h *ChatHandler) DeleteRoom(c *fiber.Ctx) error {
+	ctx := context.Background()
+
+	roomID := c.Params("roomID")
+	claims := c.Locals("claims").(*services.Claims)
+
+	room, err := h.chatService.DeleteRoom(ctx, roomID, claims.UserID)
+	if err != nil {
+		log.Printf("[DeleteRoom] failed to delete room %s: %v", roomID, err)
+		return errorResponse(c, err, "failed to delete room")
+	}
+
+	out := ws.RoomDeletedData{
+		RoomId:  roomID,
+		ActorId: claims.UserID,
+	}
+	h.hub.BroadcastToRoomAndUsers(roomID, room.MemberIDs, ws.MustMarshal(ws.WsMessage{
+		Type: ws.TypeRoomDeleted,
+		Data: ws.MustMarshal(out),
+	}))
+	h.hub.RemoveRoom(roomID)
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
 // type ChatWSHandler struct {
 // 	hub            *chatWs.Hub
 // 	httpClient     *http.Client
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/napat2224/socket-programming-chat-app/internal/services"
+)
+
+// errorResponse maps service errors to an HTTP status. Unknown errors are
+// reported as 500 without leaking their message.
+func errorResponse(c *fiber.Ctx, err error, fallback string) error {
+	status := fiber.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrRoomNotFound),
+		errors.Is(err, services.ErrRequestClosed):
+		status = fiber.StatusNotFound
+	case errors.Is(err, services.ErrForbidden),
+		errors.Is(err, services.ErrUserBanned),
+		errors.Is(err, services.ErrUserMuted),
+		errors.Is(err, services.ErrAnnouncement):
+		status = fiber.StatusForbidden
+	case errors.Is(err, services.ErrSlowMode):
+		status = fiber.StatusTooManyRequests
+	case errors.Is(err, services.ErrInvalidTarget),
+		errors.Is(err, services.ErrInvalidDuration),
+		errors.Is(err, services.ErrInvalidInput):
+		status = fiber.StatusBadRequest
+	case errors.Is(err, services.ErrInviteInvalid):
+		status = fiber.StatusGone
+	case errors.Is(err, services.ErrRoomArchived):
+		status = fiber.StatusConflict
+	}
+
+	message := fallback
+	if status != fiber.StatusInternalServerError {
+		message = err.Error()
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error": message,
+	})
+}
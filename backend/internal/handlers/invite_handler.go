@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/napat2224/socket-programming-chat-app/internal/domain"
+	"github.com/napat2224/socket-programming-chat-app/internal/services"
+	ws "github.com/napat2224/socket-programming-chat-app/internal/services/websocket"
+)
+
+type InviteHandler struct {
+	inviteService *services.InviteService
+	hub           *ws.Hub
+}
+
+func NewInviteHandler(inviteService *services.InviteService, hub *ws.Hub) *InviteHandler {
+	return &InviteHandler{
+		inviteService: inviteService,
+		hub:           hub,
+	}
+}
+
+type CreateInviteRequest struct {
+	ExpiresInSeconds int `json:"expiresInSeconds,omitempty"`
+	MaxUses          int `json:"maxUses,omitempty"`
+}
+
+func (h *InviteHandler) CreateInvite(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	roomID := c.Params("roomID")
+	claims := c.Locals("claims").(*services.Claims)
+
+	var req CreateInviteRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+		}
+	}
+
+	invite, err := h.inviteService.CreateInvite(
+		ctx,
+		roomID,
+		claims.UserID,
+		time.Duration(req.ExpiresInSeconds)*time.Second,
+		req.MaxUses,
+	)
+	if err != nil {
+		return errorResponse(c, err, "failed to create invite")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"data": invite,
+	})
+}
+
+func (h *InviteHandler) ListInvites(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	roomID := c.Params("roomID")
+	claims := c.Locals("claims").(*services.Claims)
+
+	invites, err := h.inviteService.ListInvites(ctx, roomID, claims.UserID)
+	if err != nil {
+		return errorResponse(c, err, "failed to list invites")
+	}
+
+	return c.JSON(fiber.Map{
+		"data": invites,
+	})
+}
+
+func (h *InviteHandler) RevokeInvite(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	roomID := c.Params("roomID")
+	token := c.Params("token")
+	claims := c.Locals("claims").(*services.Claims)
+
+	if err := h.inviteService.RevokeInvite(ctx, roomID, claims.UserID, token); err != nil {
+		return errorResponse(c, err, "failed to revoke invite")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *InviteHandler) RedeemInvite(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	token := c.Params("token")
+	claims := c.Locals("claims").(*services.Claims)
+
+	room, joined, err := h.inviteService.RedeemInvite(ctx, token, claims.UserID)
+	if err != nil {
+		return errorResponse(c, err, "failed to redeem invite")
+	}
+
+	if joined {
+		h.hub.AddUserToRoom(room.ID, claims.UserID)
+
+		data := ws.RoomMemberJoinedData{
+			RoomId:  room.ID,
+			UserId:  claims.UserID,
+			Name:    claims.Name,
+			Profile: domain.ProfileType(claims.Profile),
+		}
+		h.hub.BroadcastToRoom(room.ID, ws.MustMarshal(ws.WsMessage{
+			Type: ws.TypeJoinRoom,
+			Data: ws.MustMarshal(data),
+		}))
+	}
+
+	return c.JSON(fiber.Map{
+		"data": room,
+	})
+}
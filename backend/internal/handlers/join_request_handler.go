@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/napat2224/socket-programming-chat-app/internal/services"
+	ws "github.com/napat2224/socket-programming-chat-app/internal/services/websocket"
+)
+
+type JoinRequestHandler struct {
+	chatService *services.ChatService
+	hub         *ws.Hub
+}
+
+func NewJoinRequestHandler(chatService *services.ChatService, hub *ws.Hub) *JoinRequestHandler {
+	return &JoinRequestHandler{
+		chatService: chatService,
+		hub:         hub,
+	}
+}
+
+func (h *JoinRequestHandler) ListJoinRequests(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	roomID := c.Params("roomID")
+	claims := c.Locals("claims").(*services.Claims)
+
+	requests, err := h.chatService.ListJoinRequests(ctx, roomID, claims.UserID)
+	if err != nil {
+		return errorResponse(c, err, "failed to list join requests")
+	}
+
+	return c.JSON(fiber.Map{
+		"data": requests,
+	})
+}
+
+func (h *JoinRequestHandler) Approve(c *fiber.Ctx) error { return h.decide(c, true) }
+func (h *JoinRequestHandler) Reject(c *fiber.Ctx) error  { return h.decide(c, false) }
+
+func (h *JoinRequestHandler) decide(c *fiber.Ctx, approve bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	roomID := c.Params("roomID")
+	requestID := c.Params("requestID")
+	claims := c.Locals("claims").(*services.Claims)
+
+	request, user, err := h.chatService.DecideJoinRequest(ctx, roomID, requestID, claims.UserID, approve)
+	if err != nil {
+		return errorResponse(c, err, "failed to decide join request")
+	}
+
+	decision := ws.JoinRequestData{
+		RequestId: request.ID,
+		RoomId:    roomID,
+		UserId:    request.UserID,
+		Name:      user.Name,
+		Profile:   user.Profile,
+		Status:    request.Status,
+	}
+	h.hub.SendToUser(request.UserID, ws.MustMarshal(ws.WsMessage{
+		Type: ws.TypeJoinDecision,
+		Data: ws.MustMarshal(decision),
+	}))
+
+	if approve {
+		h.hub.AddUserToRoom(roomID, request.UserID)
+
+		joined := ws.RoomMemberJoinedData{
+			RoomId:  roomID,
+			UserId:  request.UserID,
+			Name:    user.Name,
+			Profile: user.Profile,
+		}
+		h.hub.BroadcastToRoom(roomID, ws.MustMarshal(ws.WsMessage{
+			Type: ws.TypeJoinRoom,
+			Data: ws.MustMarshal(joined),
+		}))
+	}
+
+	return c.JSON(fiber.Map{
+		"data": request,
+	})
+}
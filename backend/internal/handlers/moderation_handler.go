@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/napat2224/socket-programming-chat-app/internal/services"
+	ws "github.com/napat2224/socket-programming-chat-app/internal/services/websocket"
+)
+
+type ModerationHandler struct {
+	chatService *services.ChatService
+	hub         *ws.Hub
+}
+
+func NewModerationHandler(chatService *services.ChatService, hub *ws.Hub) *ModerationHandler {
+	return &ModerationHandler{
+		chatService: chatService,
+		hub:         hub,
+	}
+}
+
+type ModerationRequest struct {
+	UserID          string `json:"userId"`
+	DurationSeconds int    `json:"durationSeconds,omitempty"`
+}
+
+func (h *ModerationHandler) Kick(c *fiber.Ctx) error    { return h.moderate(c, ws.ModerationKick) }
+func (h *ModerationHandler) Ban(c *fiber.Ctx) error     { return h.moderate(c, ws.ModerationBan) }
+func (h *ModerationHandler) Unban(c *fiber.Ctx) error   { return h.moderate(c, ws.ModerationUnban) }
+func (h *ModerationHandler) Mute(c *fiber.Ctx) error    { return h.moderate(c, ws.ModerationMute) }
+func (h *ModerationHandler) Unmute(c *fiber.Ctx) error  { return h.moderate(c, ws.ModerationUnmute) }
+func (h *ModerationHandler) Promote(c *fiber.Ctx) error { return h.moderate(c, ws.ModerationPromote) }
+func (h *ModerationHandler) Demote(c *fiber.Ctx) error  { return h.moderate(c, ws.ModerationDemote) }
+
+func (h *ModerationHandler) moderate(c *fiber.Ctx, action ws.ModerationAction) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	roomID := c.Params("roomID")
+	claims := c.Locals("claims").(*services.Claims)
+	actorID := claims.UserID
+
+	var req ModerationRequest
+	if err := c.BodyParser(&req); err != nil || req.UserID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "userId is required"})
+	}
+
+	var until *time.Time
+	var err error
+	switch action {
+	case ws.ModerationKick:
+		err = h.chatService.KickMember(ctx, roomID, actorID, req.UserID)
+	case ws.ModerationBan:
+		err = h.chatService.BanMember(ctx, roomID, actorID, req.UserID)
+	case ws.ModerationUnban:
+		err = h.chatService.UnbanMember(ctx, roomID, actorID, req.UserID)
+	case ws.ModerationMute:
+		var t time.Time
+		t, err = h.chatService.MuteMember(ctx, roomID, actorID, req.UserID, time.Duration(req.DurationSeconds)*time.Second)
+		until = &t
+	case ws.ModerationUnmute:
+		err = h.chatService.UnmuteMember(ctx, roomID, actorID, req.UserID)
+	case ws.ModerationPromote:
+		err = h.chatService.SetMemberRole(ctx, roomID, actorID, req.UserID, true)
+	case ws.ModerationDemote:
+		err = h.chatService.SetMemberRole(ctx, roomID, actorID, req.UserID, false)
+	}
+	if err != nil {
+		return errorResponse(c, err, "failed to apply moderation action")
+	}
+
+	data := ws.ModerationData{
+		RoomId:   roomID,
+		Action:   action,
+		TargetId: req.UserID,
+		ActorId:  actorID,
+		Until:    until,
+	}
+
+	// Removed users stop receiving room traffic, so unsubscribe them first
+	// and let the user-level send deliver their copy of the event.
+	if action == ws.ModerationKick || action == ws.ModerationBan {
+		h.hub.RemoveUserFromRoom(roomID, req.UserID)
+	}
+	h.hub.BroadcastToRoomAndUsers(roomID, []string{req.UserID}, ws.MustMarshal(ws.WsMessage{
+		Type: ws.TypeModeration,
+		Data: ws.MustMarshal(data),
+	}))
+
+	return c.JSON(fiber.Map{
+		"data": data,
+	})
+}
+
+// GetAuditLog pages through the room's audit log, newest first. Pass the
+// returned nextCursor as ?cursor= to fetch older entries.
+func (h *ModerationHandler) GetAuditLog(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	roomID := c.Params("roomID")
+	claims := c.Locals("claims").(*services.Claims)
+
+	entries, next, err := h.chatService.GetAuditLog(ctx, roomID, claims.UserID, c.Query("cursor"), c.QueryInt("limit"))
+	if err != nil {
+		return errorResponse(c, err, "failed to get audit log")
+	}
+
+	return c.JSON(fiber.Map{
+		"data":       entries,
+		"nextCursor": next,
+	})
+}
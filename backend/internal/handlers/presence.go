@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"context"
+	"log"
+
+	ws "github.com/napat2224/socket-programming-chat-app/internal/services/websocket"
+)
+
+// announcePresence sends a user_presence event to the user's audience only,
+// so strangers never learn who is online.
+func announcePresence(hub *ws.Hub, audience []string, info ws.UserPresenceData, status ws.UserStatus) {
+	var data any = info
+	if status == ws.StatusOffline {
+		data = ws.UserOfflineData{UserId: info.UserId}
+	}
+	envelope := ws.WsMessage{
+		Type:   ws.TypeUserPresence,
+		Status: status,
+		Data:   ws.MustMarshal(data),
+	}
+	hub.SendToUsers(audience, ws.MustMarshal(envelope))
+}
+
+// announce loads the user's current audience and announces to it.
+func (h *WsHandler) announce(ctx context.Context, info ws.UserPresenceData, status ws.UserStatus) {
+	audience, err := h.chatService.PresenceAudience(ctx, info.UserId)
+	if err != nil {
+		log.Println("[ws] failed to load presence audience:", err)
+		return
+	}
+	announcePresence(h.hub, audience, info, status)
+}
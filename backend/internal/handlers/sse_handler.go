@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/napat2224/socket-programming-chat-app/internal/services"
+	ws "github.com/napat2224/socket-programming-chat-app/internal/services/websocket"
+)
+
+const sseKeepAlive = 15 * time.Second
+
+// SSEHandler is the fallback transport for networks that block WebSocket
+// upgrades: envelopes stream down as Server-Sent Events and client actions
+// are posted over REST, both through the same Hub as /ws.
+type SSEHandler struct {
+	wsHandler *WsHandler
+	hub       *ws.Hub
+}
+
+func NewSSEHandler(wsHandler *WsHandler, hub *ws.Hub) *SSEHandler {
+	return &SSEHandler{
+		wsHandler: wsHandler,
+		hub:       hub,
+	}
+}
+
+// Stream opens an event stream. Each event's data is one envelope, exactly
+// as /ws would send it; the first one is the presence snapshot carrying the
+// session id that actions are posted to.
+func (h *SSEHandler) Stream(c *fiber.Ctx) error {
+	claims, ok := c.Locals("claims").(*services.Claims)
+	if !ok || claims == nil || claims.UserID == "" {
+		return fiber.NewError(fiber.StatusUnauthorized, "Missing claims")
+	}
+
+	conn, stream := ws.NewSSEConnection(c.Query("device"), c.Get("User-Agent"))
+	go h.wsHandler.Serve(conn, claims)
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer conn.Close()
+
+		keepAlive := time.NewTicker(sseKeepAlive)
+		defer keepAlive.Stop()
+
+		writeEvent := func(msg []byte) {
+			w.WriteString("data: ")
+			w.Write(msg)
+			w.WriteString("\n\n")
+		}
+
+		for {
+			select {
+			case msg := <-stream.Messages():
+				writeEvent(msg)
+			case <-keepAlive.C:
+				w.WriteString(": keep-alive\n\n")
+			case <-stream.Done():
+				// Write what was queued before the close, including the
+				// close event itself.
+				for {
+					select {
+					case msg := <-stream.Messages():
+						writeEvent(msg)
+					default:
+						_ = w.Flush()
+						return
+					}
+				}
+			}
+			if err := w.Flush(); err != nil {
+				log.Println("[sse] stream closed:", err)
+				return
+			}
+		}
+	})
+	return nil
+}
+
+// PostAction feeds one envelope (message, react_message, join_room, ...) to
+// the caller's SSE session as if it had arrived over /ws.
+func (h *SSEHandler) PostAction(c *fiber.Ctx) error {
+	claims, ok := c.Locals("claims").(*services.Claims)
+	if !ok || claims == nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "Missing claims")
+	}
+
+	body := c.Body()
+	var envelope ws.WsMessage
+	if err := json.Unmarshal(body, &envelope); err != nil || envelope.Type == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "body must be a message envelope",
+		})
+	}
+
+	conn := h.hub.FindSession(claims.UserID, c.Params("sessionID"))
+	if conn == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "session not found",
+		})
+	}
+
+	target, ok := conn.(interface{ Deliver([]byte) error })
+	if !ok {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": ws.ErrNotDeliverable.Error(),
+		})
+	}
+
+	// The body buffer is reused by fasthttp once the handler returns.
+	msg := make([]byte, len(body))
+	copy(msg, body)
+	if err := target.Deliver(msg); err != nil {
+		status := fiber.StatusServiceUnavailable
+		if errors.Is(err, ws.ErrNotDeliverable) {
+			status = fiber.StatusConflict
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.SendStatus(fiber.StatusAccepted)
+}
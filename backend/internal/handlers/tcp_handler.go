@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"time"
+
+	"github.com/napat2224/socket-programming-chat-app/internal/services"
+	ws "github.com/napat2224/socket-programming-chat-app/internal/services/websocket"
+)
+
+const tcpHandshakeTimeout = 10 * time.Second
+
+// TCPHandler serves the line-delimited JSON protocol over plain TCP. Each
+// line is a WsMessage envelope; after an auth handshake the connection is
+// registered in the same Hub as WebSocket clients.
+type TCPHandler struct {
+	wsHandler   *WsHandler
+	authService *services.FirebaseAuth
+}
+
+func NewTCPHandler(wsHandler *WsHandler, authService *services.FirebaseAuth) *TCPHandler {
+	return &TCPHandler{
+		wsHandler:   wsHandler,
+		authService: authService,
+	}
+}
+
+// ListenAndServe accepts TCP clients on addr until the listener fails.
+func (h *TCPHandler) ListenAndServe(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	log.Printf("[tcp] listening on %s", addr)
+	return h.Serve(ln)
+}
+
+// Serve accepts TCP clients from ln until it is closed. Other accept errors,
+// such as running out of file descriptors, are retried with backoff like
+// net/http does.
+func (h *TCPHandler) Serve(ln net.Listener) error {
+	var delay time.Duration
+	for {
+		c, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			if delay == 0 {
+				delay = 5 * time.Millisecond
+			} else {
+				delay = min(2*delay, time.Second)
+			}
+			log.Printf("[tcp] accept error: %v; retrying in %s", err, delay)
+			time.Sleep(delay)
+			continue
+		}
+		delay = 0
+		go h.handle(c)
+	}
+}
+
+func (h *TCPHandler) handle(c net.Conn) {
+	scanner := ws.NewTCPScanner(c)
+
+	_ = c.SetReadDeadline(time.Now().Add(tcpHandshakeTimeout))
+	if !scanner.Scan() {
+		log.Println("[tcp] no handshake from", c.RemoteAddr())
+		_ = c.Close()
+		return
+	}
+	_ = c.SetReadDeadline(time.Time{})
+
+	var envelope ws.WsMessage
+	var in ws.IncomingAuthData
+	if err := json.Unmarshal(scanner.Bytes(), &envelope); err != nil || envelope.Type != ws.TypeAuth {
+		rejectTCP(c, "expected auth handshake")
+		return
+	}
+	if err := json.Unmarshal(envelope.Data, &in); err != nil {
+		rejectTCP(c, "invalid auth data")
+		return
+	}
+
+	claims, err := h.verify(in)
+	if err != nil || claims == nil || claims.UserID == "" {
+		log.Println("[tcp] handshake rejected:", err)
+		rejectTCP(c, "invalid token")
+		return
+	}
+
+	conn := ws.NewTCPConnection(c, scanner, in.Device)
+	ok := ws.WsMessage{
+		Type: ws.TypeAuth,
+		Data: ws.MustMarshal(ws.AuthOkData{
+			UserId:  claims.UserID,
+			Session: conn.Session(),
+		}),
+	}
+	if err := conn.Send(ws.MustMarshal(ok)); err != nil {
+		log.Println("[tcp] failed to ack handshake:", err)
+		conn.Close()
+		return
+	}
+
+	h.wsHandler.Serve(conn, claims)
+}
+
+// verify mirrors AuthMiddleware.AddClaims for the handshake line.
+func (h *TCPHandler) verify(in ws.IncomingAuthData) (*services.Claims, error) {
+	if in.Mode == "test" {
+		return &services.Claims{
+			UserID:  "test",
+			Email:   "test@test.com",
+			Name:    "Test User",
+			Profile: 1,
+		}, nil
+	}
+	if in.Token == "" {
+		return nil, errors.New("missing token")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return h.authService.VerifyIDToken(ctx, in.Token)
+}
+
+func rejectTCP(c net.Conn, reason string) {
+	envelope := ws.WsMessage{
+		Type: ws.TypeError,
+		Data: ws.MustMarshal(ws.ErrorData{
+			Code:    ws.ErrCodeUnauthorized,
+			Message: reason,
+			Request: ws.TypeAuth,
+		}),
+	}
+	_ = c.SetWriteDeadline(time.Now().Add(time.Second))
+	_, _ = c.Write(append(ws.MustMarshal(envelope), '\n'))
+	_ = c.Close()
+}
@@ -8,10 +8,13 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/napat2224/socket-programming-chat-app/internal/domain"
 	"github.com/napat2224/socket-programming-chat-app/internal/services"
+	ws "github.com/napat2224/socket-programming-chat-app/internal/services/websocket"
 )
 
 type UserHandler struct {
 	userService *services.UserService
+	chatService *services.ChatService
+	hub         *ws.Hub
 }
 
 type VerifyTokenResponse struct {
@@ -21,9 +24,11 @@ type VerifyTokenResponse struct {
 	Profile int    `json:"profile"`
 }
 
-func NewUserHandler(userService *services.UserService) *UserHandler {
+func NewUserHandler(userService *services.UserService, chatService *services.ChatService, hub *ws.Hub) *UserHandler {
 	return &UserHandler{
 		userService: userService,
+		chatService: chatService,
+		hub:         hub,
 	}
 }
 
@@ -47,6 +52,16 @@ type CheckUsernameResponse struct {
 	Message   string `json:"message,omitempty"`
 }
 
+type UserProfileResponse struct {
+	UserID     string             `json:"userId"`
+	Name       string             `json:"name"`
+	Profile    domain.ProfileType `json:"profile"`
+	Online     bool               `json:"online,omitempty"`
+	Status     ws.UserStatus      `json:"status,omitempty"`
+	StatusText string             `json:"statusText,omitempty"`
+	LastSeenAt *time.Time         `json:"lastSeenAt,omitempty"`
+}
+
 func (h *UserHandler) Register(c *fiber.Ctx) error {
 	var req RegisterRequest
 	log.Println("Register called")
@@ -147,3 +162,148 @@ func (h *UserHandler) CheckUsername(c *fiber.Ctx) error {
 		Available: true,
 	})
 }
+
+// UpdatePresence changes the caller's status, status text or "appear
+// offline" setting and tells the users who share a room with them.
+func (h *UserHandler) UpdatePresence(c *fiber.Ctx) error {
+	claims, ok := c.Locals("claims").(*services.Claims)
+	if !ok || claims == nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "Missing claims")
+	}
+
+	var req domain.PresenceUpdate
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	user, err := h.userService.UpdatePresence(ctx, claims.UserID, req)
+	if err != nil {
+		log.Printf("Error updating presence: %v", err)
+		return errorResponse(c, err, "failed to update presence")
+	}
+	if user == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "user not found",
+		})
+	}
+
+	// Apply the status while the old visibility still holds so that
+	// revealing announces the new status and hiding announces offline.
+	status, text, expiresAt := user.CurrentStatus(time.Now())
+	info, statusChanged := h.hub.SetStatus(user.UserID, ws.UserStatus(status), text, expiresAt)
+	hiddenChanged := h.hub.SetAppearOffline(user.UserID, user.AppearOffline)
+	if (statusChanged || hiddenChanged) && h.hub.IsOnline(user.UserID) {
+		audience, err := h.chatService.PresenceAudience(ctx, user.UserID)
+		if err != nil {
+			log.Printf("Error loading presence audience: %v", err)
+		}
+		if statusChanged {
+			announcePresence(h.hub, audience, info, info.Status)
+		}
+		if hiddenChanged {
+			info, _ = h.hub.Presence(user.UserID)
+			announced := info.Status
+			if user.AppearOffline {
+				announced = ws.StatusOffline
+				// Freeze last seen at the moment the user went invisible.
+				if err := h.userService.RecordLastSeen(ctx, []string{user.UserID}, time.Now()); err != nil {
+					log.Printf("Error recording last seen: %v", err)
+				}
+			}
+			announcePresence(h.hub, audience, info, announced)
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"data": user,
+	})
+}
+
+// GetUser returns another user's public profile. Presence and last seen are
+// included only when the caller shares a room with them.
+func (h *UserHandler) GetUser(c *fiber.Ctx) error {
+	claims, ok := c.Locals("claims").(*services.Claims)
+	if !ok || claims == nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "Missing claims")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	user, err := h.userService.GetUser(ctx, c.Params("userID"))
+	if err != nil {
+		log.Printf("Error getting user: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to get user",
+		})
+	}
+	if user == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "user not found",
+		})
+	}
+
+	resp := UserProfileResponse{
+		UserID:  user.UserID,
+		Name:    user.Name,
+		Profile: user.Profile,
+	}
+	shares, err := h.chatService.SharesRoom(ctx, claims.UserID, user.UserID)
+	if err != nil {
+		log.Printf("Error checking shared rooms: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to get user",
+		})
+	}
+	if !shares {
+		return c.JSON(fiber.Map{
+			"data": resp,
+		})
+	}
+
+	resp.Status = ws.StatusOffline
+	resp.LastSeenAt = user.VisibleLastSeen()
+	if h.hub.AppearsOnline(user.UserID) {
+		if info, ok := h.hub.Presence(user.UserID); ok {
+			resp.Online = true
+			resp.Status = info.Status
+			resp.StatusText = info.StatusText
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"data": resp,
+	})
+}
+
+// GetSessions lists the caller's connected devices.
+func (h *UserHandler) GetSessions(c *fiber.Ctx) error {
+	claims, ok := c.Locals("claims").(*services.Claims)
+	if !ok || claims == nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "Missing claims")
+	}
+
+	return c.JSON(fiber.Map{
+		"data": h.hub.Sessions(claims.UserID),
+	})
+}
+
+// TerminateSession disconnects one of the caller's devices.
+func (h *UserHandler) TerminateSession(c *fiber.Ctx) error {
+	claims, ok := c.Locals("claims").(*services.Claims)
+	if !ok || claims == nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "Missing claims")
+	}
+
+	if !h.hub.CloseSession(claims.UserID, c.Params("sessionID")) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "session not found",
+		})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -3,7 +3,9 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
+	"time"
 
 	"github.com/gofiber/websocket/v2"
 
@@ -15,12 +17,31 @@ import (
 type WsHandler struct {
 	hub         *ws.Hub
 	chatService *services.ChatService
+	userService *services.UserService
+	compression ws.Compression
+	limiter     *ws.RateLimiter
 }
 
-func NewWsHandler(hub *ws.Hub, chatService *services.ChatService) *WsHandler {
+func NewWsHandler(hub *ws.Hub, chatService *services.ChatService, userService *services.UserService, compression ws.Compression, limits ws.RateLimits) *WsHandler {
+	if err := compression.Validate(); err != nil {
+		log.Printf("[ws] invalid compression config, disabling it: %v", err)
+		compression.Enabled = false
+	}
 	return &WsHandler{
 		hub:         hub,
 		chatService: chatService,
+		userService: userService,
+		compression: compression,
+		limiter:     ws.NewRateLimiter(limits),
+	}
+}
+
+// UpgradeConfig is the handshake configuration for /ws: the codecs clients
+// may pick and whether permessage-deflate is offered.
+func (h *WsHandler) UpgradeConfig() websocket.Config {
+	return websocket.Config{
+		Subprotocols:      ws.Subprotocols,
+		EnableCompression: h.compression.Enabled,
 	}
 }
 
@@ -33,17 +54,31 @@ func (h *WsHandler) Handle(c *websocket.Conn) {
 		return
 	}
 
-	userId := claims.UserID
-	name := claims.Name
-	p := domain.ProfileType(claims.Profile)
-
-	if userId == "" {
+	if claims.UserID == "" {
 		log.Println("[ws] empty userId from claims, closing connection")
 		_ = c.Close()
 		return
 	}
 
 	conn := ws.NewConnection(c)
+	if err := conn.SetCompression(h.compression); err != nil {
+		log.Println("[ws] failed to enable compression:", err)
+	}
+	h.Serve(conn, claims)
+}
+
+// Serve runs an authenticated connection until it disconnects, whatever
+// transport it arrived on.
+func (h *WsHandler) Serve(conn *ws.Connection, claims *services.Claims) {
+	userId := claims.UserID
+	name := claims.Name
+	p := domain.ProfileType(claims.Profile)
+
+	if wait, blocked := h.limiter.Blocked(userId, time.Now()); blocked {
+		h.sendError(conn, "", "", &ws.RateLimitError{Scope: ws.ScopeUser, RetryAfter: wait, Disconnect: true})
+		conn.CloseWithCode(ws.CloseRateLimited, "rate limited")
+		return
+	}
 
 	presence := ws.UserPresenceData{
 		UserId:  userId,
@@ -51,10 +86,31 @@ func (h *WsHandler) Handle(c *websocket.Conn) {
 		Profile: p,
 	}
 
-	h.hub.AddUser(presence, conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	user, err := h.userService.GetMe(ctx, userId)
+	if err != nil {
+		log.Println("[ws] failed to load user:", err)
+	}
+	audience, err := h.chatService.PresenceAudience(ctx, userId)
+	if err != nil {
+		log.Println("[ws] failed to load presence audience:", err)
+	}
+	cancel()
+
+	if user != nil {
+		status, text, expiresAt := user.CurrentStatus(time.Now())
+		presence.Status = ws.UserStatus(status)
+		presence.StatusText = text
+		presence.StatusExpiresAt = expiresAt
+		h.hub.SetAppearOffline(userId, user.AppearOffline)
+	}
+	first := h.hub.AddUser(presence, conn)
+	h.subscribeToUserRooms(conn, userId)
 
+	session := conn.Session()
 	snapshot := ws.PresenceSnapshotData{
-		Users: h.hub.OnlineUsers(),
+		Users:   h.hub.VisibleOnlineUsers(audience),
+		Session: &session,
 	}
 	snapMsg := ws.WsMessage{
 		Type:   ws.TypePresenceSnapshot,
@@ -65,26 +121,26 @@ func (h *WsHandler) Handle(c *websocket.Conn) {
 		log.Println("[ws] failed to send snapshot:", err)
 	}
 
-	onlineEnvelope := ws.WsMessage{
-		Type:   ws.TypeUserPresence,
-		Status: ws.StatusOnline,
-		Data:   ws.MustMarshal(presence),
+	if first && !h.hub.IsAppearingOffline(userId) {
+		if info, ok := h.hub.Presence(userId); ok {
+			announcePresence(h.hub, audience, info, info.Status)
+		}
 	}
-	h.hub.BroadcastToAllExcept(conn, ws.MustMarshal(onlineEnvelope))
 
+	// Room membership changes while connected, so later announcements
+	// reload the audience rather than reusing the one loaded above.
 	defer func() {
+		hidden := h.hub.IsAppearingOffline(userId)
 		userId, last := h.hub.Remove(conn)
-		if userId != "" && last {
-			data := ws.UserOfflineData{
-				UserId: userId,
-			}
-			envelope := ws.WsMessage{
-				Type:   ws.TypeUserPresence,
-				Status: ws.StatusOffline,
-				Data:   ws.MustMarshal(data),
+		if userId != "" && last && !hidden {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			h.announce(ctx, presence, ws.StatusOffline)
+			if err := h.userService.RecordLastSeen(ctx, []string{userId}, time.Now()); err != nil {
+				log.Println("[ws] failed to record last seen:", err)
 			}
-			h.hub.BroadcastToAll(ws.MustMarshal(envelope))
+			cancel()
 		}
+		h.limiter.Forget(conn)
 		conn.Close()
 	}()
 
@@ -94,10 +150,25 @@ func (h *WsHandler) Handle(c *websocket.Conn) {
 			log.Println("[ws] read error:", err)
 			break
 		}
+		if info, changed := h.hub.Touch(conn, time.Now()); changed {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			h.announce(ctx, info, info.Status)
+			cancel()
+		}
 
 		var envelope ws.WsMessage
-		if err := json.Unmarshal(raw, &envelope); err != nil {
-			log.Println("[ws] invalid ws message:", err)
+		decodeErr := json.Unmarshal(raw, &envelope)
+		if limited := h.limiter.Allow(conn, userId, envelope.Type, time.Now()); limited != nil {
+			h.sendError(conn, envelope.Type, "", limited)
+			if limited.Disconnect {
+				log.Printf("[ws] disconnecting %s for exceeding rate limits", userId)
+				conn.CloseWithCode(ws.CloseRateLimited, "rate limited")
+				return
+			}
+			continue
+		}
+		if decodeErr != nil {
+			log.Println("[ws] invalid ws message:", decodeErr)
 			continue
 		}
 
@@ -114,12 +185,54 @@ func (h *WsHandler) Handle(c *websocket.Conn) {
 		case ws.TypeJoinRoom:
 			h.handleJoinRoom(conn, envelope)
 
+		case ws.TypeSubscribe:
+			h.handleSubscribe(conn, envelope)
+
+		case ws.TypeUnsubscribe:
+			h.handleUnsubscribe(conn, envelope)
+
+		case ws.TypeActivity:
+			// Only marks the connection active, which Touch already did.
+
 		default:
 			log.Println("[ws] unknown message type:", envelope.Type)
 		}
 	}
 }
 
+const (
+	presenceIdleAfter     = 5 * time.Minute
+	presenceSweepInterval = 30 * time.Second
+	lastSeenInterval      = 2 * time.Minute
+)
+
+// RunIdleMonitor periodically marks users away once all their connections
+// are idle, expires custom statuses and announces the changes. It also
+// refreshes the last-seen time of visible users so it survives a crash. It
+// returns when ctx is done.
+func (h *WsHandler) RunIdleMonitor(ctx context.Context) {
+	ticker := time.NewTicker(presenceSweepInterval)
+	defer ticker.Stop()
+	heartbeat := time.NewTicker(lastSeenInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-heartbeat.C:
+			if err := h.userService.RecordLastSeen(ctx, h.hub.VisibleUserIDs(), now); err != nil {
+				log.Println("[ws] failed to record last seen:", err)
+			}
+		case now := <-ticker.C:
+			h.limiter.Prune(now)
+			for _, info := range h.hub.SweepIdle(now, presenceIdleAfter) {
+				h.announce(ctx, info, info.Status)
+			}
+		}
+	}
+}
+
 func (h *WsHandler) handleTextMessage(conn *ws.Connection, envelope ws.WsMessage) {
 	var in ws.IncomingTextData
 	if err := json.Unmarshal(envelope.Data, &in); err != nil {
@@ -149,6 +262,7 @@ func (h *WsHandler) handleTextMessage(conn *ws.Connection, envelope ws.WsMessage
 	)
 	if err != nil {
 		log.Println("[ws] failed to save message:", err)
+		h.sendError(conn, ws.TypeTextMessage, in.RoomId, err)
 		return
 	}
 	if msg == nil {
@@ -189,13 +303,21 @@ func (h *WsHandler) handleReactMessage(conn *ws.Connection, envelope ws.WsMessag
 		return
 	}
 
+	reactorId := h.hub.UserIDForConn(conn)
+	if reactorId == "" {
+		log.Println("[ws] reaction from unknown user")
+		return
+	}
+
 	msg, err := h.chatService.AddReaction(
 		context.Background(),
 		in.MessageId,
+		reactorId,
 		in.ReactType,
 	)
 	if err != nil {
 		log.Println("[ws] failed to add reaction:", err)
+		h.sendError(conn, ws.TypeReactMessage, "", err)
 		return
 	}
 
@@ -226,20 +348,31 @@ func (h *WsHandler) handleCreateRoom(conn *ws.Connection, envelope ws.WsMessage)
 		return
 	}
 
+	visibility := in.Visibility
+	if !visibility.IsValid() {
+		visibility = domain.VisibilityPublic
+		if in.IsPublic != nil && !*in.IsPublic {
+			visibility = domain.VisibilityPrivate
+		}
+	}
+
 	room, err := h.chatService.CreateRoom(
 		context.Background(),
 		creatorId,
-		[]string{},
+		in.MemberIds,
 		in.ChatName,
 		in.Background,
-		true,
+		visibility,
 	)
 	if err != nil {
 		log.Println("[ws] failed to create room:", err)
+		h.sendError(conn, ws.TypeCreateRoom, "", err)
 		return
 	}
 
-	h.hub.AddToRoom(room.ID, conn)
+	for _, memberId := range room.MemberIDs {
+		h.hub.AddUserToRoom(room.ID, memberId)
+	}
 
 	out := ws.OutgoingCreateRoomData{
 		RoomId:     room.ID,
@@ -247,6 +380,7 @@ func (h *WsHandler) handleCreateRoom(conn *ws.Connection, envelope ws.WsMessage)
 		ChatName:   room.RoomName,
 		UserId:     room.MemberIDs,
 		Background: room.BackgroundColor,
+		IsPublic:   room.IsPublic,
 	}
 
 	outEnvelope := ws.WsMessage{
@@ -255,7 +389,13 @@ func (h *WsHandler) handleCreateRoom(conn *ws.Connection, envelope ws.WsMessage)
 		Data:   ws.MustMarshal(out),
 	}
 
-	h.hub.BroadcastToAll(ws.MustMarshal(outEnvelope))
+	// Public rooms show up in everyone's directory; private rooms are only
+	// announced to their members.
+	if room.IsPublic {
+		h.hub.BroadcastToAll(ws.MustMarshal(outEnvelope))
+	} else {
+		h.hub.SendToUsers(room.MemberIDs, ws.MustMarshal(outEnvelope))
+	}
 }
 
 func (h *WsHandler) handleJoinRoom(conn *ws.Connection, envelope ws.WsMessage) {
@@ -271,16 +411,39 @@ func (h *WsHandler) handleJoinRoom(conn *ws.Connection, envelope ws.WsMessage) {
 		return
 	}
 
-	err := h.chatService.JoinRoom(context.Background(), in.RoomId, userId)
+	room, pending, err := h.chatService.JoinRoom(context.Background(), in.RoomId, userId)
 	if err != nil {
 		log.Println("[ws] failed to join room:", err)
+		h.sendError(conn, ws.TypeJoinRoom, in.RoomId, err)
 		return
 	}
 
-	h.hub.AddToRoom(in.RoomId, conn)
-
 	userInfo, _ := h.hub.UserInfo(userId)
 
+	if pending != nil {
+		request := ws.JoinRequestData{
+			RequestId: pending.Request.ID,
+			RoomId:    in.RoomId,
+			UserId:    userId,
+			Name:      userInfo.Name,
+			Profile:   userInfo.Profile,
+			Status:    pending.Request.Status,
+		}
+		requestEnvelope := ws.MustMarshal(ws.WsMessage{
+			Type: ws.TypeJoinRequest,
+			Data: ws.MustMarshal(request),
+		})
+		if err := conn.Send(requestEnvelope); err != nil {
+			log.Println("[ws] failed to send join request ack:", err)
+		}
+		if pending.Created {
+			h.hub.SendToUsers(room.Admins(), requestEnvelope)
+		}
+		return
+	}
+
+	h.hub.AddToRoom(in.RoomId, conn)
+
 	joined := ws.RoomMemberJoinedData{
 		RoomId:  in.RoomId,
 		UserId:  userId,
@@ -296,3 +459,118 @@ func (h *WsHandler) handleJoinRoom(conn *ws.Connection, envelope ws.WsMessage) {
 
 	h.hub.BroadcastToRoom(in.RoomId, ws.MustMarshal(outEnvelope))
 }
+
+// subscribeToUserRooms subscribes a fresh connection to every room the user
+// belongs to, so clients no longer have to join each room to receive it.
+func (h *WsHandler) subscribeToUserRooms(conn *ws.Connection, userId string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rooms, err := h.chatService.GetUserRooms(ctx, userId)
+	if err != nil {
+		log.Println("[ws] failed to load user rooms:", err)
+		return
+	}
+	for _, room := range rooms {
+		if room.IsBanned(userId) {
+			continue
+		}
+		h.hub.AddToRoom(room.ID, conn)
+	}
+}
+
+func (h *WsHandler) handleSubscribe(conn *ws.Connection, envelope ws.WsMessage) {
+	var in ws.IncomingSubscribeData
+	if err := json.Unmarshal(envelope.Data, &in); err != nil {
+		log.Println("[ws] invalid subscribe data:", err)
+		return
+	}
+
+	userId := h.hub.UserIDForConn(conn)
+	if userId == "" {
+		log.Println("[ws] subscribe from unknown user")
+		return
+	}
+
+	if err := h.chatService.CanSubscribe(context.Background(), in.RoomId, userId); err != nil {
+		h.sendError(conn, ws.TypeSubscribe, in.RoomId, err)
+		return
+	}
+
+	h.hub.AddToRoom(in.RoomId, conn)
+	h.sendSubscription(conn, ws.TypeSubscribe, in.RoomId, true)
+}
+
+func (h *WsHandler) handleUnsubscribe(conn *ws.Connection, envelope ws.WsMessage) {
+	var in ws.IncomingSubscribeData
+	if err := json.Unmarshal(envelope.Data, &in); err != nil {
+		log.Println("[ws] invalid unsubscribe data:", err)
+		return
+	}
+
+	h.hub.RemoveFromRoom(in.RoomId, conn)
+	h.sendSubscription(conn, ws.TypeUnsubscribe, in.RoomId, false)
+}
+
+func (h *WsHandler) sendSubscription(conn *ws.Connection, request ws.MessageType, roomId string, subscribed bool) {
+	envelope := ws.WsMessage{
+		Type: request,
+		Data: ws.MustMarshal(ws.SubscriptionData{
+			RoomId:     roomId,
+			Subscribed: subscribed,
+		}),
+	}
+	if err := conn.Send(ws.MustMarshal(envelope)); err != nil {
+		log.Println("[ws] failed to send subscription ack:", err)
+	}
+}
+
+// sendError reports a rejected request back to the connection that sent it.
+func (h *WsHandler) sendError(conn *ws.Connection, request ws.MessageType, roomId string, err error) {
+	data := ws.ErrorData{
+		Code:    ws.ErrCodeInternal,
+		Message: "internal error",
+		Request: request,
+		RoomId:  roomId,
+	}
+
+	var muted *services.MutedError
+	var slow *services.SlowModeError
+	var limited *ws.RateLimitError
+	switch {
+	case errors.As(err, &limited):
+		retryAt := time.Now().Add(limited.RetryAfter)
+		data.Code = ws.ErrCodeRateLimited
+		data.RetryAt = &retryAt
+		data.RetryAfterMs = limited.RetryAfter.Milliseconds()
+	case errors.As(err, &muted):
+		data.Code = ws.ErrCodeMuted
+		data.RetryAt = &muted.Until
+	case errors.As(err, &slow):
+		retryAt := time.Now().Add(slow.RetryAfter)
+		data.Code = ws.ErrCodeSlowMode
+		data.RetryAt = &retryAt
+		data.RetryAfterMs = slow.RetryAfter.Milliseconds()
+	case errors.Is(err, services.ErrAnnouncement):
+		data.Code = ws.ErrCodeAnnouncement
+	case errors.Is(err, services.ErrUserBanned):
+		data.Code = ws.ErrCodeBanned
+	case errors.Is(err, services.ErrRoomArchived):
+		data.Code = ws.ErrCodeArchived
+	case errors.Is(err, services.ErrRoomNotFound):
+		data.Code = ws.ErrCodeRoomNotFound
+	case errors.Is(err, services.ErrForbidden):
+		data.Code = ws.ErrCodeForbidden
+	}
+	if data.Code != ws.ErrCodeInternal {
+		data.Message = err.Error()
+	}
+
+	envelope := ws.WsMessage{
+		Type: ws.TypeError,
+		Data: ws.MustMarshal(data),
+	}
+	if err := conn.Send(ws.MustMarshal(envelope)); err != nil {
+		log.Println("[ws] failed to send error:", err)
+	}
+}
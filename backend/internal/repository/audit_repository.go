@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/napat2224/socket-programming-chat-app/internal/domain"
+	"github.com/napat2224/socket-programming-chat-app/internal/repository/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AuditRepository is append-only: entries are inserted and read, never
+// updated or deleted.
+type AuditRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoAuditRepository(db *mongo.Database, collectionName string) *AuditRepository {
+	collection := db.Collection(collectionName)
+
+	return &AuditRepository{
+		collection: collection,
+	}
+}
+
+func (r *AuditRepository) Append(ctx context.Context, entry *domain.AuditEntry) error {
+	model, err := models.AuditToModel(entry)
+	if err != nil {
+		return err
+	}
+	_, err = r.collection.InsertOne(ctx, model)
+	return err
+}
+
+// FindByRoomID returns up to limit entries older than the entry with id
+// before (newest first when before is empty) and the cursor for the next
+// page, which is "" on the last page.
+func (r *AuditRepository) FindByRoomID(ctx context.Context, roomID string, before string, limit int) ([]*domain.AuditEntry, string, error) {
+	roomOID, err := primitive.ObjectIDFromHex(roomID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	filter := bson.M{"room_id": roomOID}
+	if before != "" {
+		beforeOID, err := primitive.ObjectIDFromHex(before)
+		if err != nil {
+			return nil, "", ErrInvalidCursor
+		}
+		filter["_id"] = bson.M{"$lt": beforeOID}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetLimit(int64(limit + 1))
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []*models.AuditModel
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if len(entries) > limit {
+		entries = entries[:limit]
+		next = entries[len(entries)-1].ID.Hex()
+	}
+
+	result := make([]*domain.AuditEntry, 0, len(entries))
+	for _, m := range entries {
+		result = append(result, m.ToDomain())
+	}
+	return result, next, nil
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/napat2224/socket-programming-chat-app/internal/domain"
+	"github.com/napat2224/socket-programming-chat-app/internal/repository/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type InviteRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoInviteRepository(db *mongo.Database, collectionName string) *InviteRepository {
+	collection := db.Collection(collectionName)
+
+	return &InviteRepository{
+		collection: collection,
+	}
+}
+
+func (r *InviteRepository) SaveInvite(ctx context.Context, invite *domain.RoomInvite) (*domain.RoomInvite, error) {
+	model, err := models.InviteToModel(invite)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.collection.InsertOne(ctx, model); err != nil {
+		return nil, err
+	}
+	return model.ToDomain(), nil
+}
+
+func (r *InviteRepository) FindByToken(ctx context.Context, token string) (*domain.RoomInvite, error) {
+	var model models.InviteModel
+	if err := r.collection.FindOne(ctx, bson.M{"_id": token}).Decode(&model); err != nil {
+		return nil, err
+	}
+	return model.ToDomain(), nil
+}
+
+func (r *InviteRepository) FindByRoomID(ctx context.Context, roomID string) ([]*domain.RoomInvite, error) {
+	roomOID, err := primitive.ObjectIDFromHex(roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"room_id": roomOID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var invites []*models.InviteModel
+	if err := cursor.All(ctx, &invites); err != nil {
+		return nil, err
+	}
+
+	result := make([]*domain.RoomInvite, 0, len(invites))
+	for _, m := range invites {
+		result = append(result, m.ToDomain())
+	}
+	return result, nil
+}
+
+func (r *InviteRepository) Revoke(ctx context.Context, token string) error {
+	res, err := r.collection.UpdateOne(ctx, bson.M{"_id": token}, bson.M{"$set": bson.M{"revoked": true}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// ConsumeUse atomically bumps the use counter of a still-usable invite.
+// It returns mongo.ErrNoDocuments when the invite is revoked, expired or
+// exhausted, so two concurrent redeems can never exceed max_uses.
+func (r *InviteRepository) ConsumeUse(ctx context.Context, token string, now time.Time) (*domain.RoomInvite, error) {
+	filter := bson.M{
+		"_id":     token,
+		"revoked": false,
+		"$and": []bson.M{
+			{"$or": []bson.M{
+				{"expires_at": bson.M{"$exists": false}},
+				{"expires_at": bson.M{"$gt": now}},
+			}},
+			{"$or": []bson.M{
+				{"max_uses": 0},
+				{"$expr": bson.M{"$lt": []string{"$uses", "$max_uses"}}},
+			}},
+		},
+	}
+	update := bson.M{"$inc": bson.M{"uses": 1}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var model models.InviteModel
+	if err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&model); err != nil {
+		return nil, err
+	}
+	return model.ToDomain(), nil
+}
+
+func (r *InviteRepository) DeleteByRoomID(ctx context.Context, roomID string) error {
+	roomOID, err := primitive.ObjectIDFromHex(roomID)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.DeleteMany(ctx, bson.M{"room_id": roomOID})
+	return err
+}
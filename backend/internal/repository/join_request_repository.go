@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/napat2224/socket-programming-chat-app/internal/domain"
+	"github.com/napat2224/socket-programming-chat-app/internal/repository/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type JoinRequestRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoJoinRequestRepository(db *mongo.Database, collectionName string) *JoinRequestRepository {
+	collection := db.Collection(collectionName)
+
+	// At most one pending request per user and room, so concurrent join
+	// attempts cannot both insert one.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "room_id", Value: 1}, {Key: "user_id", Value: 1}},
+		Options: options.Index().
+			SetName("unique_pending_request").
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"status": string(domain.JoinRequestPending)}),
+	})
+	if err != nil {
+		log.Printf("failed to create join request index: %v", err)
+	}
+
+	return &JoinRequestRepository{
+		collection: collection,
+	}
+}
+
+// CreatePending returns the user's pending request for the room, creating it
+// if none exists, so repeated join attempts don't pile up duplicates.
+func (r *JoinRequestRepository) CreatePending(ctx context.Context, roomID string, userID string) (*domain.JoinRequest, bool, error) {
+	roomOID, err := primitive.ObjectIDFromHex(roomID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	filter := bson.M{
+		"room_id": roomOID,
+		"user_id": userID,
+		"status":  string(domain.JoinRequestPending),
+	}
+	update := bson.M{"$setOnInsert": bson.M{
+		"_id":        primitive.NewObjectID(),
+		"created_at": time.Now(),
+	}}
+	opts := options.Update().SetUpsert(true)
+
+	created := false
+	res, err := r.collection.UpdateOne(ctx, filter, update, opts)
+	switch {
+	case mongo.IsDuplicateKeyError(err):
+		// A concurrent attempt inserted the pending request first.
+	case err != nil:
+		return nil, false, err
+	default:
+		created = res.UpsertedCount > 0
+	}
+
+	var model models.JoinRequestModel
+	if err := r.collection.FindOne(ctx, filter).Decode(&model); err != nil {
+		return nil, false, err
+	}
+	return model.ToDomain(), created, nil
+}
+
+func (r *JoinRequestRepository) FindByID(ctx context.Context, requestID string) (*domain.JoinRequest, error) {
+	oid, err := primitive.ObjectIDFromHex(requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	var model models.JoinRequestModel
+	if err := r.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&model); err != nil {
+		return nil, err
+	}
+	return model.ToDomain(), nil
+}
+
+func (r *JoinRequestRepository) FindPendingByRoomID(ctx context.Context, roomID string) ([]*domain.JoinRequest, error) {
+	roomOID, err := primitive.ObjectIDFromHex(roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"room_id": roomOID, "status": string(domain.JoinRequestPending)}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var requests []*models.JoinRequestModel
+	if err := cursor.All(ctx, &requests); err != nil {
+		return nil, err
+	}
+
+	result := make([]*domain.JoinRequest, 0, len(requests))
+	for _, m := range requests {
+		result = append(result, m.ToDomain())
+	}
+	return result, nil
+}
+
+// Decide moves a pending request to its final status. It returns
+// mongo.ErrNoDocuments if the request was already decided.
+func (r *JoinRequestRepository) Decide(ctx context.Context, requestID string, status domain.JoinRequestStatus, decidedBy string) (*domain.JoinRequest, error) {
+	oid, err := primitive.ObjectIDFromHex(requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"_id": oid, "status": string(domain.JoinRequestPending)}
+	update := bson.M{"$set": bson.M{
+		"status":     string(status),
+		"decided_by": decidedBy,
+		"decided_at": time.Now(),
+	}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var model models.JoinRequestModel
+	if err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&model); err != nil {
+		return nil, err
+	}
+	return model.ToDomain(), nil
+}
+
+func (r *JoinRequestRepository) DeleteByRoomID(ctx context.Context, roomID string) error {
+	roomOID, err := primitive.ObjectIDFromHex(roomID)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.DeleteMany(ctx, bson.M{"room_id": roomOID})
+	return err
+}
@@ -73,6 +73,40 @@ func (r *MessageRepository) FindMessagesByRoomID(ctx context.Context, roomID str
 	return domainMessages, nil
 }
 
+// FindLastBySender returns the sender's most recent message in the room, or
+// nil if they have not posted there.
+func (r *MessageRepository) FindLastBySender(ctx context.Context, roomID string, senderID string) (*domain.Message, error) {
+	roomOID, err := primitive.ObjectIDFromHex(roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"room_id": roomOID, "sender_id": senderID}
+	opts := options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	var model models.MessageModel
+	if err := r.collection.FindOne(ctx, filter, opts).Decode(&model); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return model.ToDomain(), nil
+}
+
+func (r *MessageRepository) FindByID(ctx context.Context, messageID string) (*domain.Message, error) {
+	oid, err := primitive.ObjectIDFromHex(messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	var model models.MessageModel
+	if err := r.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&model); err != nil {
+		return nil, err
+	}
+	return model.ToDomain(), nil
+}
+
 func (r *MessageRepository) AddReaction(
 	ctx context.Context,
 	messageID string,
@@ -105,3 +139,13 @@ func (r *MessageRepository) AddReaction(
 
 	return model.ToDomain(), nil
 }
+
+func (r *MessageRepository) DeleteByRoomID(ctx context.Context, roomID string) error {
+	roomOID, err := primitive.ObjectIDFromHex(roomID)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.DeleteMany(ctx, bson.M{"room_id": roomOID})
+	return err
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/napat2224/socket-programming-chat-app/internal/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AuditModel struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	RoomID    primitive.ObjectID `bson:"room_id"`
+	ActorID   string             `bson:"actor_id"`
+	Action    string             `bson:"action"`
+	TargetID  string             `bson:"target_id,omitempty"`
+	Before    map[string]any     `bson:"before,omitempty"`
+	After     map[string]any     `bson:"after,omitempty"`
+	CreatedAt time.Time          `bson:"created_at"`
+}
+
+func (m *AuditModel) ToDomain() *domain.AuditEntry {
+	return &domain.AuditEntry{
+		ID:        m.ID.Hex(),
+		RoomID:    m.RoomID.Hex(),
+		ActorID:   m.ActorID,
+		Action:    domain.AuditAction(m.Action),
+		TargetID:  m.TargetID,
+		Before:    m.Before,
+		After:     m.After,
+		CreatedAt: m.CreatedAt,
+	}
+}
+
+func AuditToModel(entry *domain.AuditEntry) (*AuditModel, error) {
+	roomID, err := primitive.ObjectIDFromHex(entry.RoomID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuditModel{
+		ID:        primitive.NewObjectID(),
+		RoomID:    roomID,
+		ActorID:   entry.ActorID,
+		Action:    string(entry.Action),
+		TargetID:  entry.TargetID,
+		Before:    entry.Before,
+		After:     entry.After,
+		CreatedAt: entry.CreatedAt,
+	}, nil
+}
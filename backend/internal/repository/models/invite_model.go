@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/napat2224/socket-programming-chat-app/internal/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type InviteModel struct {
+	Token     string             `bson:"_id"`
+	RoomID    primitive.ObjectID `bson:"room_id"`
+	CreatedBy string             `bson:"created_by"`
+	ExpiresAt *time.Time         `bson:"expires_at,omitempty"`
+	MaxUses   int                `bson:"max_uses"`
+	Uses      int                `bson:"uses"`
+	Revoked   bool               `bson:"revoked"`
+	CreatedAt time.Time          `bson:"created_at"`
+}
+
+func (m *InviteModel) ToDomain() *domain.RoomInvite {
+	return &domain.RoomInvite{
+		Token:     m.Token,
+		RoomID:    m.RoomID.Hex(),
+		CreatedBy: m.CreatedBy,
+		ExpiresAt: m.ExpiresAt,
+		MaxUses:   m.MaxUses,
+		Uses:      m.Uses,
+		Revoked:   m.Revoked,
+		CreatedAt: m.CreatedAt,
+	}
+}
+
+func InviteToModel(invite *domain.RoomInvite) (*InviteModel, error) {
+	roomID, err := primitive.ObjectIDFromHex(invite.RoomID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &InviteModel{
+		Token:     invite.Token,
+		RoomID:    roomID,
+		CreatedBy: invite.CreatedBy,
+		ExpiresAt: invite.ExpiresAt,
+		MaxUses:   invite.MaxUses,
+		Uses:      invite.Uses,
+		Revoked:   invite.Revoked,
+		CreatedAt: invite.CreatedAt,
+	}, nil
+}
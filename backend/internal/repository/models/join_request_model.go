@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/napat2224/socket-programming-chat-app/internal/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type JoinRequestModel struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	RoomID    primitive.ObjectID `bson:"room_id"`
+	UserID    string             `bson:"user_id"`
+	Status    string             `bson:"status"`
+	DecidedBy string             `bson:"decided_by,omitempty"`
+	CreatedAt time.Time          `bson:"created_at"`
+	DecidedAt *time.Time         `bson:"decided_at,omitempty"`
+}
+
+func (m *JoinRequestModel) ToDomain() *domain.JoinRequest {
+	return &domain.JoinRequest{
+		ID:        m.ID.Hex(),
+		RoomID:    m.RoomID.Hex(),
+		UserID:    m.UserID,
+		Status:    domain.JoinRequestStatus(m.Status),
+		DecidedBy: m.DecidedBy,
+		CreatedAt: m.CreatedAt,
+		DecidedAt: m.DecidedAt,
+	}
+}
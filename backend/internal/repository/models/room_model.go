@@ -8,24 +8,47 @@ import (
 )
 
 type RoomModel struct {
-	ID              primitive.ObjectID `bson:"_id" json:"id"` // MongoDB auto-generates if omitted
-	CreatorID       string             `bson:"creator_id" json:"creatorId"`
-	MemberIDs       []string           `bson:"member_ids" json:"memberIds"`
-	RoomName        string             `bson:"room_name,omitempty" json:"roomName,omitempty"`
-	BackgroundColor string             `bson:"background_color,omitempty" json:"backgroundColor,omitempty"`
-	LastMessageSent time.Time          `bson:"last_message_sent,omitempty" json:"lastMessageSent,omitempty"`
-	IsPublic        bool               `bson:"is_public" json:"isPublic"`
+	ID               primitive.ObjectID   `bson:"_id" json:"id"` // MongoDB auto-generates if omitted
+	CreatorID        string               `bson:"creator_id" json:"creatorId"`
+	MemberIDs        []string             `bson:"member_ids" json:"memberIds"`
+	AdminIDs         []string             `bson:"admin_ids,omitempty" json:"adminIds,omitempty"`
+	BannedIDs        []string             `bson:"banned_ids,omitempty" json:"bannedIds,omitempty"`
+	MutedUntil       map[string]time.Time `bson:"muted_until,omitempty" json:"mutedUntil,omitempty"`
+	RoomName         string               `bson:"room_name,omitempty" json:"roomName,omitempty"`
+	Topic            string               `bson:"topic,omitempty" json:"topic,omitempty"`
+	Description      string               `bson:"description,omitempty" json:"description,omitempty"`
+	AvatarURL        string               `bson:"avatar_url,omitempty" json:"avatarUrl,omitempty"`
+	Tags             []string             `bson:"tags,omitempty" json:"tags,omitempty"`
+	BackgroundColor  string               `bson:"background_color,omitempty" json:"backgroundColor,omitempty"`
+	LastMessageSent  time.Time            `bson:"last_message_sent,omitempty" json:"lastMessageSent,omitempty"`
+	IsPublic         bool                 `bson:"is_public" json:"isPublic"`
+	RequiresApproval bool                 `bson:"requires_approval,omitempty" json:"requiresApproval,omitempty"`
+	ArchivedAt       *time.Time           `bson:"archived_at,omitempty" json:"archivedAt,omitempty"`
+	AnnouncementOnly bool                 `bson:"announcement_only,omitempty" json:"announcementOnly,omitempty"`
+	SlowModeSeconds  int                  `bson:"slow_mode_seconds,omitempty" json:"slowModeSeconds,omitempty"`
 }
 
 func (r *RoomModel) ToDomain() *domain.Room {
 	return &domain.Room{
-		ID:              r.ID.Hex(),
-		CreatorID:       r.CreatorID,
-		MemberIDs:       r.MemberIDs,
-		RoomName:        r.RoomName,
-		BackgroundColor: domain.BackgroundColor(r.BackgroundColor),
-		LastMessageSent: r.LastMessageSent,
-		IsPublic:        r.IsPublic,
+		ID:               r.ID.Hex(),
+		CreatorID:        r.CreatorID,
+		MemberIDs:        r.MemberIDs,
+		AdminIDs:         r.AdminIDs,
+		BannedIDs:        r.BannedIDs,
+		MutedUntil:       r.MutedUntil,
+		RoomName:         r.RoomName,
+		Topic:            r.Topic,
+		Description:      r.Description,
+		AvatarURL:        r.AvatarURL,
+		Tags:             r.Tags,
+		BackgroundColor:  domain.BackgroundColor(r.BackgroundColor),
+		LastMessageSent:  r.LastMessageSent,
+		IsPublic:         r.IsPublic,
+		RequiresApproval: r.RequiresApproval,
+		ArchivedAt:       r.ArchivedAt,
+		AnnouncementOnly: r.AnnouncementOnly,
+		SlowModeSeconds:  r.SlowModeSeconds,
+		CreatedAt:        r.ID.Timestamp(),
 	}
 }
 
@@ -44,12 +67,23 @@ func RoomToModel(room *domain.Room) (*RoomModel, error) {
 	}
 
 	return &RoomModel{
-		ID:              id,
-		CreatorID:       room.CreatorID,
-		MemberIDs:       room.MemberIDs,
-		RoomName:        room.RoomName,
-		BackgroundColor: string(room.BackgroundColor),
-		LastMessageSent: room.LastMessageSent,
-		IsPublic:        room.IsPublic,
+		ID:               id,
+		CreatorID:        room.CreatorID,
+		MemberIDs:        room.MemberIDs,
+		AdminIDs:         room.AdminIDs,
+		BannedIDs:        room.BannedIDs,
+		MutedUntil:       room.MutedUntil,
+		RoomName:         room.RoomName,
+		Topic:            room.Topic,
+		Description:      room.Description,
+		AvatarURL:        room.AvatarURL,
+		Tags:             room.Tags,
+		BackgroundColor:  string(room.BackgroundColor),
+		LastMessageSent:  room.LastMessageSent,
+		IsPublic:         room.IsPublic,
+		RequiresApproval: room.RequiresApproval,
+		ArchivedAt:       room.ArchivedAt,
+		AnnouncementOnly: room.AnnouncementOnly,
+		SlowModeSeconds:  room.SlowModeSeconds,
 	}, nil
 }
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/napat2224/socket-programming-chat-app/internal/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type RoomPreferenceModel struct {
+	UserID                  string             `bson:"user_id"`
+	RoomID                  primitive.ObjectID `bson:"room_id"`
+	NotificationsMutedUntil *time.Time         `bson:"notifications_muted_until,omitempty"`
+	Favourite               bool               `bson:"favourite"`
+	Hidden                  bool               `bson:"hidden"`
+	Nickname                string             `bson:"nickname,omitempty"`
+	UpdatedAt               time.Time          `bson:"updated_at"`
+}
+
+func (m *RoomPreferenceModel) ToDomain() *domain.RoomPreference {
+	return &domain.RoomPreference{
+		UserID:                  m.UserID,
+		RoomID:                  m.RoomID.Hex(),
+		NotificationsMutedUntil: m.NotificationsMutedUntil,
+		Favourite:               m.Favourite,
+		Hidden:                  m.Hidden,
+		Nickname:                m.Nickname,
+		UpdatedAt:               m.UpdatedAt,
+	}
+}
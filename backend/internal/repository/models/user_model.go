@@ -1,6 +1,10 @@
 package models
 
-import "github.com/napat2224/socket-programming-chat-app/internal/domain"
+import (
+	"time"
+
+	"github.com/napat2224/socket-programming-chat-app/internal/domain"
+)
 
 // Model for saving into gorm
 type UserModel struct {
@@ -8,6 +12,13 @@ type UserModel struct {
 	Name    string `bson:"name"`
 	Email   string `bson:"email"`
 	Profile int `bson:"profile"`
+
+	AppearOffline   bool       `bson:"appear_offline,omitempty"`
+	Status          string     `bson:"status,omitempty"`
+	StatusText      string     `bson:"status_text,omitempty"`
+	StatusExpiresAt *time.Time `bson:"status_expires_at,omitempty"`
+	LastSeenAt      *time.Time `bson:"last_seen_at,omitempty"`
+	HideLastSeen    bool       `bson:"hide_last_seen,omitempty"`
 }
 
 func (u *UserModel) ToDomain() *domain.User {
@@ -19,5 +30,11 @@ func (u *UserModel) ToDomain() *domain.User {
 		UserID:      u.UserID,
 		Name:    u.Name,
 		Profile: domain.ProfileType(u.Profile), // adjust type if needed
+		AppearOffline:   u.AppearOffline,
+		Status:          domain.PresenceStatus(u.Status),
+		StatusText:      u.StatusText,
+		StatusExpiresAt: u.StatusExpiresAt,
+		LastSeenAt:      u.LastSeenAt,
+		HideLastSeen:    u.HideLastSeen,
 	}
 }
\ No newline at end of file
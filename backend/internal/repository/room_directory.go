@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/napat2224/socket-programming-chat-app/internal/domain"
+	"github.com/napat2224/socket-programming-chat-app/internal/repository/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// directoryCursor is the position after the last room of a page: the sort
+// key of that room plus its id as a tie-breaker.
+type directoryCursor struct {
+	Key int64  `json:"k,omitempty"`
+	ID  string `json:"id"`
+}
+
+type directoryRow struct {
+	models.RoomModel `bson:",inline"`
+	MemberCount      int64     `bson:"member_count"`
+	ActivityAt       time.Time `bson:"activity_at"`
+}
+
+// SearchPublicRooms returns one page of non-archived public rooms, newest,
+// largest or most recently active first, and the cursor for the next page
+// ("" on the last page).
+func (r *RoomRepository) SearchPublicRooms(ctx context.Context, query domain.RoomDirectoryQuery) ([]*domain.Room, string, error) {
+	match := bson.M{"is_public": true, "archived_at": bson.M{"$exists": false}}
+	if query.Search != "" {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(query.Search), Options: "i"}
+		match["$or"] = bson.A{
+			bson.M{"room_name": pattern},
+			bson.M{"topic": pattern},
+		}
+	}
+	if len(query.Tags) > 0 {
+		match["tags"] = bson.M{"$all": query.Tags}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$addFields", Value: bson.M{
+			"member_count": bson.M{"$size": bson.M{"$ifNull": bson.A{"$member_ids", bson.A{}}}},
+			"activity_at":  bson.M{"$ifNull": bson.A{"$last_message_sent", bson.M{"$toDate": "$_id"}}},
+		}}},
+	}
+
+	sortKey := ""
+	switch query.Sort {
+	case domain.RoomSortMembers:
+		sortKey = "member_count"
+	case domain.RoomSortActivity:
+		sortKey = "activity_at"
+	}
+
+	if query.Cursor != "" {
+		after, err := decodeDirectoryCursor(query.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		lastID, err := primitive.ObjectIDFromHex(after.ID)
+		if err != nil {
+			return nil, "", ErrInvalidCursor
+		}
+
+		var page bson.M
+		switch sortKey {
+		case "":
+			page = bson.M{"_id": bson.M{"$lt": lastID}}
+		default:
+			var key any = after.Key
+			if sortKey == "activity_at" {
+				key = time.UnixMilli(after.Key)
+			}
+			page = bson.M{"$or": bson.A{
+				bson.M{sortKey: bson.M{"$lt": key}},
+				bson.M{sortKey: key, "_id": bson.M{"$lt": lastID}},
+			}}
+		}
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: page}})
+	}
+
+	sort := bson.D{{Key: "_id", Value: -1}}
+	if sortKey != "" {
+		sort = bson.D{{Key: sortKey, Value: -1}, {Key: "_id", Value: -1}}
+	}
+	pipeline = append(pipeline,
+		bson.D{{Key: "$sort", Value: sort}},
+		// Fetch one extra row to learn whether another page exists.
+		bson.D{{Key: "$limit", Value: query.Limit + 1}},
+	)
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, "", err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []*directoryRow
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if len(rows) > query.Limit {
+		rows = rows[:query.Limit]
+		last := rows[len(rows)-1]
+		c := directoryCursor{ID: last.ID.Hex()}
+		switch sortKey {
+		case "member_count":
+			c.Key = last.MemberCount
+		case "activity_at":
+			c.Key = last.ActivityAt.UnixMilli()
+		}
+		next = encodeDirectoryCursor(c)
+	}
+
+	rooms := make([]*domain.Room, 0, len(rows))
+	for _, row := range rows {
+		rooms = append(rooms, row.ToDomain())
+	}
+	return rooms, next, nil
+}
+
+func encodeDirectoryCursor(c directoryCursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeDirectoryCursor(s string) (directoryCursor, error) {
+	var c directoryCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(b, &c); err != nil || c.ID == "" {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
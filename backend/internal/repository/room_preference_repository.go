@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/napat2224/socket-programming-chat-app/internal/domain"
+	"github.com/napat2224/socket-programming-chat-app/internal/repository/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RoomPreferenceRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoRoomPreferenceRepository(db *mongo.Database, collectionName string) *RoomPreferenceRepository {
+	collection := db.Collection(collectionName)
+
+	return &RoomPreferenceRepository{
+		collection: collection,
+	}
+}
+
+// FindByUser returns the user's preferences for the given rooms keyed by
+// room id. Rooms without stored preferences are absent from the map.
+func (r *RoomPreferenceRepository) FindByUser(ctx context.Context, userID string, roomIDs []string) (map[string]*domain.RoomPreference, error) {
+	roomOIDs := make([]primitive.ObjectID, 0, len(roomIDs))
+	for _, id := range roomIDs {
+		oid, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			continue
+		}
+		roomOIDs = append(roomOIDs, oid)
+	}
+
+	result := make(map[string]*domain.RoomPreference, len(roomOIDs))
+	if len(roomOIDs) == 0 {
+		return result, nil
+	}
+
+	filter := bson.M{"user_id": userID, "room_id": bson.M{"$in": roomOIDs}}
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var prefs []*models.RoomPreferenceModel
+	if err := cursor.All(ctx, &prefs); err != nil {
+		return nil, err
+	}
+	for _, m := range prefs {
+		result[m.RoomID.Hex()] = m.ToDomain()
+	}
+	return result, nil
+}
+
+// Update applies the partial update and returns the stored preference,
+// creating it on first use.
+func (r *RoomPreferenceRepository) Update(ctx context.Context, userID string, roomID string, update domain.RoomPreferenceUpdate, now time.Time) (*domain.RoomPreference, error) {
+	roomOID, err := primitive.ObjectIDFromHex(roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	set := bson.M{"updated_at": now}
+	unset := bson.M{}
+	if update.MuteSeconds != nil {
+		if *update.MuteSeconds > 0 {
+			set["notifications_muted_until"] = now.Add(time.Duration(*update.MuteSeconds) * time.Second)
+		} else {
+			unset["notifications_muted_until"] = ""
+		}
+	}
+	if update.Favourite != nil {
+		set["favourite"] = *update.Favourite
+	}
+	if update.Hidden != nil {
+		set["hidden"] = *update.Hidden
+	}
+	if update.Nickname != nil {
+		if *update.Nickname != "" {
+			set["nickname"] = *update.Nickname
+		} else {
+			unset["nickname"] = ""
+		}
+	}
+
+	doc := bson.M{"$set": set}
+	if len(unset) > 0 {
+		doc["$unset"] = unset
+	}
+
+	filter := bson.M{"user_id": userID, "room_id": roomOID}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var model models.RoomPreferenceModel
+	if err := r.collection.FindOneAndUpdate(ctx, filter, doc, opts).Decode(&model); err != nil {
+		return nil, err
+	}
+	return model.ToDomain(), nil
+}
+
+func (r *RoomPreferenceRepository) DeleteByRoomID(ctx context.Context, roomID string) error {
+	roomOID, err := primitive.ObjectIDFromHex(roomID)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.DeleteMany(ctx, bson.M{"room_id": roomOID})
+	return err
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"log"
+	"time"
 
 	"github.com/napat2224/socket-programming-chat-app/internal/domain"
 	"github.com/napat2224/socket-programming-chat-app/internal/repository/models"
@@ -35,8 +36,9 @@ func (r *RoomRepository) SaveRoom(ctx context.Context, room *domain.Room) (*doma
 		return nil, err
 	}
 
-	roomIDStr := res.InsertedID.(primitive.ObjectID).Hex()
-	room.ID = roomIDStr
+	oid := res.InsertedID.(primitive.ObjectID)
+	room.ID = oid.Hex()
+	room.CreatedAt = oid.Timestamp()
 	return room, nil
 }
 
@@ -76,14 +78,10 @@ func (r *RoomRepository) IsUserInRoom(ctx context.Context, userID string, roomID
 	return count > 0, nil
 }
 
-// !!!!!!!!!!!!!!!!! FIX ME: check in member array !!!!!!!!!!!!!!!!!
 func (r *RoomRepository) GetChatRoomsByUserID(ctx context.Context, userID string) ([]*domain.Room, error) {
-	filter := bson.M{"$or": []bson.M{
-		{"prophet_id": userID},
-		{"customer_id": userID}, //check in member ids array instead
-	}}
+	filter := bson.M{"member_ids": userID}
 
-	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}) // newest first
+	findOptions := options.Find().SetSort(bson.D{{Key: "last_message_sent", Value: -1}, {Key: "_id", Value: -1}}) // most recently active first
 	cursor, err := r.collection.Find(ctx, filter, findOptions)
 	if err != nil {
 		log.Printf("[GetChatRoomsByUserID] DB Find error: %v", err)
@@ -105,8 +103,8 @@ func (r *RoomRepository) GetChatRoomsByUserID(ctx context.Context, userID string
 }
 
 func (r *RoomRepository) GetAllPublicRooms(ctx context.Context) ([]*domain.Room, error) {
-	filter := bson.M{"is_public": true}
-	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}) // newest first
+	filter := bson.M{"is_public": true, "archived_at": bson.M{"$exists": false}}
+	findOptions := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}) // newest first
 	cursor, err := r.collection.Find(ctx, filter, findOptions)
 	if err != nil {
 		log.Printf("[GetAllPublicRooms] DB Find error: %v", err)
@@ -182,20 +180,132 @@ func (r *RoomRepository) GetChatRoomsByRoomID(ctx context.Context, roomID string
 	return room.ToDomain(), nil
 }
 
-func (r *RoomRepository) UpdateRoom(ctx context.Context, roomID string, background string) (*domain.Room, error) {
+// UpdateRoom applies the non-nil settings and returns the updated room.
+func (r *RoomRepository) UpdateRoom(ctx context.Context, roomID string, settings domain.RoomSettings) (*domain.Room, error) {
 	roomOID, err := primitive.ObjectIDFromHex(roomID)
 	if err != nil {
-		log.Printf("[GetChatRoomsByRoomID] invalid roomID %s: %v", roomID, err)
+		log.Printf("[UpdateRoom] invalid roomID %s: %v", roomID, err)
 		return nil, err
 	}
 
+	set := bson.M{}
+	if settings.RoomName != nil {
+		set["room_name"] = *settings.RoomName
+	}
+	if settings.Topic != nil {
+		set["topic"] = *settings.Topic
+	}
+	if settings.Description != nil {
+		set["description"] = *settings.Description
+	}
+	if settings.AvatarURL != nil {
+		set["avatar_url"] = *settings.AvatarURL
+	}
+	if settings.BackgroundColor != nil {
+		set["background_color"] = string(*settings.BackgroundColor)
+	}
+	if settings.Tags != nil {
+		set["tags"] = *settings.Tags
+	}
+	if settings.AnnouncementOnly != nil {
+		set["announcement_only"] = *settings.AnnouncementOnly
+	}
+	if settings.SlowModeSeconds != nil {
+		set["slow_mode_seconds"] = *settings.SlowModeSeconds
+	}
+
 	filter := bson.M{"_id": roomOID}
-	update := bson.M{"$set": bson.M{"backgroundColor": background}}
+	update := bson.M{"$set": set}
 
 	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
-	var room domain.Room
+	var room models.RoomModel
 	if err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&room); err != nil {
 		return nil, err
 	}
-	return &room, nil
+	return room.ToDomain(), nil
+}
+
+func (r *RoomRepository) updateByID(ctx context.Context, roomID string, update bson.M) error {
+	objID, err := primitive.ObjectIDFromHex(roomID)
+	if err != nil {
+		return err
+	}
+
+	res, err := r.collection.UpdateOne(ctx, bson.M{"_id": objID}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// RemoveMember drops the user from the member list and revokes any admin role.
+func (r *RoomRepository) RemoveMember(ctx context.Context, roomID string, userID string) error {
+	return r.updateByID(ctx, roomID, bson.M{
+		"$pull": bson.M{"member_ids": userID, "admin_ids": userID},
+	})
+}
+
+func (r *RoomRepository) BanMember(ctx context.Context, roomID string, userID string) error {
+	return r.updateByID(ctx, roomID, bson.M{
+		"$pull":     bson.M{"member_ids": userID, "admin_ids": userID},
+		"$addToSet": bson.M{"banned_ids": userID},
+	})
+}
+
+func (r *RoomRepository) UnbanMember(ctx context.Context, roomID string, userID string) error {
+	return r.updateByID(ctx, roomID, bson.M{
+		"$pull": bson.M{"banned_ids": userID},
+	})
+}
+
+func (r *RoomRepository) MuteMember(ctx context.Context, roomID string, userID string, until time.Time) error {
+	return r.updateByID(ctx, roomID, bson.M{
+		"$set": bson.M{"muted_until." + userID: until},
+	})
+}
+
+func (r *RoomRepository) UnmuteMember(ctx context.Context, roomID string, userID string) error {
+	return r.updateByID(ctx, roomID, bson.M{
+		"$unset": bson.M{"muted_until." + userID: ""},
+	})
+}
+
+func (r *RoomRepository) SetAdmin(ctx context.Context, roomID string, userID string, admin bool) error {
+	if admin {
+		return r.updateByID(ctx, roomID, bson.M{"$addToSet": bson.M{"admin_ids": userID}})
+	}
+	return r.updateByID(ctx, roomID, bson.M{"$pull": bson.M{"admin_ids": userID}})
+}
+
+// SetArchived marks the room read-only, or clears the mark when archivedAt is nil.
+func (r *RoomRepository) SetArchived(ctx context.Context, roomID string, archivedAt *time.Time) error {
+	if archivedAt == nil {
+		return r.updateByID(ctx, roomID, bson.M{"$unset": bson.M{"archived_at": ""}})
+	}
+	return r.updateByID(ctx, roomID, bson.M{"$set": bson.M{"archived_at": *archivedAt}})
+}
+
+func (r *RoomRepository) DeleteRoom(ctx context.Context, roomID string) error {
+	objID, err := primitive.ObjectIDFromHex(roomID)
+	if err != nil {
+		return err
+	}
+
+	res, err := r.collection.DeleteOne(ctx, bson.M{"_id": objID})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *RoomRepository) TouchLastMessage(ctx context.Context, roomID string, sentAt time.Time) error {
+	return r.updateByID(ctx, roomID, bson.M{
+		"$max": bson.M{"last_message_sent": sentAt},
+	})
 }
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"log"
+	"time"
 
 	"github.com/napat2224/socket-programming-chat-app/internal/domain"
 	"github.com/napat2224/socket-programming-chat-app/internal/repository/models"
@@ -53,10 +54,16 @@ func (r *UserRepository) FindById(ctx context.Context, userId string) (*domain.U
 	}
 
 	user := &domain.User{
-		UserID:  userModel.UserID,
-		Email:   userModel.Email,
-		Name:    userModel.Name,
-		Profile: domain.ProfileType(userModel.Profile),
+		UserID:          userModel.UserID,
+		Email:           userModel.Email,
+		Name:            userModel.Name,
+		Profile:         domain.ProfileType(userModel.Profile),
+		AppearOffline:   userModel.AppearOffline,
+		Status:          domain.PresenceStatus(userModel.Status),
+		StatusText:      userModel.StatusText,
+		StatusExpiresAt: userModel.StatusExpiresAt,
+		LastSeenAt:      userModel.LastSeenAt,
+		HideLastSeen:    userModel.HideLastSeen,
 	}
 
 	return user, nil
@@ -112,9 +119,12 @@ func (r *UserRepository) GetUsersByIDs(ctx context.Context, ids []string) ([]*do
 		}
 
 		u := &domain.User{
-			UserID:  m.UserID,
-			Name:    m.Name,
-			Profile: domain.ProfileType(m.Profile),
+			UserID:        m.UserID,
+			Name:          m.Name,
+			Profile:       domain.ProfileType(m.Profile),
+			AppearOffline: m.AppearOffline,
+			LastSeenAt:    m.LastSeenAt,
+			HideLastSeen:  m.HideLastSeen,
 		}
 
 		res = append(res, u)
@@ -126,3 +136,15 @@ func (r *UserRepository) GetUsersByIDs(ctx context.Context, ids []string) ([]*do
 
 	return res, nil
 }
+
+// TouchLastSeen moves the last-seen time of the users forward to at.
+func (r *UserRepository) TouchLastSeen(ctx context.Context, userIDs []string, at time.Time) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	_, err := r.collection.UpdateMany(ctx,
+		bson.M{"user_id": bson.M{"$in": userIDs}},
+		bson.M{"$max": bson.M{"last_seen_at": at}},
+	)
+	return err
+}
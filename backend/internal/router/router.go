@@ -14,6 +14,10 @@ func SetupRoutes(
 	userHandler *handlers.UserHandler,
 	wsHandler *handlers.WsHandler,
 	chatHandler *handlers.ChatHandler,
+	moderationHandler *handlers.ModerationHandler,
+	inviteHandler *handlers.InviteHandler,
+	joinRequestHandler *handlers.JoinRequestHandler,
+	sseHandler *handlers.SSEHandler,
 ) {
 	app.Get("/health", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{"status": "healthy"})
@@ -21,13 +25,18 @@ func SetupRoutes(
 	app.Get(
 		"/ws",
 		authMiddleware.AddClaims,
-		websocket.New(wsHandler.Handle),
+		websocket.New(wsHandler.Handle, wsHandler.UpgradeConfig()),
 	)
+	app.Get("/sse", authMiddleware.AddClaims, sseHandler.Stream)
+	app.Post("/sse/:sessionID/actions", authMiddleware.AddClaims, sseHandler.PostAction)
 	api := app.Group("/api")
 
 	setupUserRoutes(api, userHandler, authMiddleware)
 	setupTestRouter(api, authMiddleware)
 	setupChatRoutes(api, chatHandler, authMiddleware)
+	setupModerationRoutes(api, moderationHandler, authMiddleware)
+	setupInviteRoutes(api, inviteHandler, authMiddleware)
+	setupJoinRequestRoutes(api, joinRequestHandler, authMiddleware)
 	// setupWebsocketRoutes(app, hub, authMiddleware)
 }
 
@@ -37,6 +46,10 @@ func setupUserRoutes(api fiber.Router, userHandler *handlers.UserHandler, authMi
 	users.Post("/check-name", userHandler.CheckUsername)
 	users.Post("/register", userHandler.Register)
 	users.Get("/me", authMiddleware.AddClaims, userHandler.GetMe)
+	users.Patch("/me/presence", authMiddleware.AddClaims, userHandler.UpdatePresence)
+	users.Get("/me/sessions", authMiddleware.AddClaims, userHandler.GetSessions)
+	users.Delete("/me/sessions/:sessionID", authMiddleware.AddClaims, userHandler.TerminateSession)
+	users.Get("/:userID", authMiddleware.AddClaims, userHandler.GetUser)
 }
 
 func setupChatRoutes(api fiber.Router, chatHandler *handlers.ChatHandler, authMiddleware *middleware.AuthMiddleware) {
@@ -44,16 +57,50 @@ func setupChatRoutes(api fiber.Router, chatHandler *handlers.ChatHandler, authMi
 
 	rooms := api.Group("/rooms")
 	rooms.Get("/public", authMiddleware.AddClaims, chatHandler.GetPublicRooms)
+	rooms.Get("/mine", authMiddleware.AddClaims, chatHandler.GetMyRooms)
 	rooms.Get("/private/:targetID", authMiddleware.AddClaims, chatHandler.GetPrivateRoomByTargetID)
 	rooms.Get("/:roomID/messages", authMiddleware.AddClaims, chatHandler.GetMessagesByRoomID)
+	rooms.Get("/:roomID/preferences", authMiddleware.AddClaims, chatHandler.GetRoomPreference)
+	rooms.Patch("/:roomID/preferences", authMiddleware.AddClaims, chatHandler.UpdateRoomPreference)
 	rooms.Get("/:roomID", authMiddleware.AddClaims, chatHandler.GetChatRoomByRoomID)
-	rooms.Patch("/:roomID", authMiddleware.AddClaims, chatHandler.UpdateBackgroundRoom)
+	rooms.Patch("/:roomID", authMiddleware.AddClaims, chatHandler.UpdateRoomSettings)
+	rooms.Delete("/:roomID", authMiddleware.AddClaims, chatHandler.DeleteRoom)
+	rooms.Post("/:roomID/archive", authMiddleware.AddClaims, chatHandler.ArchiveRoom)
+	rooms.Post("/:roomID/unarchive", authMiddleware.AddClaims, chatHandler.UnarchiveRoom)
 	// chats.Get("/:roomID/messages", r.authMiddleware.AddClaims, r.chatHandler.GetMessagesByRoomID)
 	// chats.Post("/rooms", r.authMiddleware.AddClaims, r.chatHandler.CreateRoom)
 	// chats.Get("/customer/rooms", r.authMiddleware.AddClaims, r.chatHandler.GetChatRoomsByCustomerID)
 	// chats.Get("/prophet/rooms", r.authMiddleware.AddClaims, r.chatHandler.GetChatRoomsByProphetID)
 }
 
+func setupModerationRoutes(api fiber.Router, moderationHandler *handlers.ModerationHandler, authMiddleware *middleware.AuthMiddleware) {
+	rooms := api.Group("/rooms/:roomID", authMiddleware.AddClaims)
+	rooms.Post("/kick", moderationHandler.Kick)
+	rooms.Post("/ban", moderationHandler.Ban)
+	rooms.Post("/unban", moderationHandler.Unban)
+	rooms.Post("/mute", moderationHandler.Mute)
+	rooms.Post("/unmute", moderationHandler.Unmute)
+	rooms.Post("/promote", moderationHandler.Promote)
+	rooms.Post("/demote", moderationHandler.Demote)
+	rooms.Get("/audit", moderationHandler.GetAuditLog)
+}
+
+func setupInviteRoutes(api fiber.Router, inviteHandler *handlers.InviteHandler, authMiddleware *middleware.AuthMiddleware) {
+	roomInvites := api.Group("/rooms/:roomID/invites", authMiddleware.AddClaims)
+	roomInvites.Post("/", inviteHandler.CreateInvite)
+	roomInvites.Get("/", inviteHandler.ListInvites)
+	roomInvites.Delete("/:token", inviteHandler.RevokeInvite)
+
+	api.Post("/invites/:token/redeem", authMiddleware.AddClaims, inviteHandler.RedeemInvite)
+}
+
+func setupJoinRequestRoutes(api fiber.Router, joinRequestHandler *handlers.JoinRequestHandler, authMiddleware *middleware.AuthMiddleware) {
+	requests := api.Group("/rooms/:roomID/join-requests", authMiddleware.AddClaims)
+	requests.Get("/", joinRequestHandler.ListJoinRequests)
+	requests.Post("/:requestID/approve", joinRequestHandler.Approve)
+	requests.Post("/:requestID/reject", joinRequestHandler.Reject)
+}
+
 func setupTestRouter(api fiber.Router, authMiddleware *middleware.AuthMiddleware) {
 	api.Post("/test-auth", authMiddleware.AddClaims, func(c *fiber.Ctx) error {
 		var body map[string]interface{}
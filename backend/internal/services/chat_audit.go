@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/napat2224/socket-programming-chat-app/internal/domain"
+	"github.com/napat2224/socket-programming-chat-app/internal/repository"
+)
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 200
+)
+
+// recordAudit appends an audit entry. The administrative action has already
+// happened, so a failed write is logged rather than returned.
+func recordAudit(ctx context.Context, repo *repository.AuditRepository, entry domain.AuditEntry) {
+	entry.CreatedAt = time.Now()
+	if err := repo.Append(ctx, &entry); err != nil {
+		log.Printf("[audit] failed to record %s in room %s: %v", entry.Action, entry.RoomID, err)
+	}
+}
+
+// GetAuditLog returns a page of the room's audit log, newest first. Only
+// room admins may read it.
+func (s *ChatService) GetAuditLog(ctx context.Context, roomID, actorID, cursor string, limit int) ([]*domain.AuditEntry, string, error) {
+	room, err := s.getRoom(ctx, roomID)
+	if err != nil {
+		return nil, "", err
+	}
+	if !room.IsAdmin(actorID) {
+		return nil, "", ErrForbidden
+	}
+
+	if limit <= 0 {
+		limit = defaultAuditPageSize
+	}
+	if limit > maxAuditPageSize {
+		limit = maxAuditPageSize
+	}
+
+	entries, next, err := s.auditRepo.FindByRoomID(ctx, roomID, cursor, limit)
+	if errors.Is(err, repository.ErrInvalidCursor) {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	return entries, next, err
+}
+
+// roomSettingsDiff returns the before and after values of every setting
+// that differs between the two rooms.
+func roomSettingsDiff(before, after *domain.Room) (map[string]any, map[string]any) {
+	oldValues := map[string]any{}
+	newValues := map[string]any{}
+	diff := func(field string, changed bool, oldValue, newValue any) {
+		if changed {
+			oldValues[field] = oldValue
+			newValues[field] = newValue
+		}
+	}
+
+	diff("roomName", before.RoomName != after.RoomName, before.RoomName, after.RoomName)
+	diff("topic", before.Topic != after.Topic, before.Topic, after.Topic)
+	diff("description", before.Description != after.Description, before.Description, after.Description)
+	diff("avatarUrl", before.AvatarURL != after.AvatarURL, before.AvatarURL, after.AvatarURL)
+	diff("backgroundColor", before.BackgroundColor != after.BackgroundColor, before.BackgroundColor, after.BackgroundColor)
+	diff("tags", !slices.Equal(before.Tags, after.Tags), before.Tags, after.Tags)
+	diff("announcementOnly", before.AnnouncementOnly != after.AnnouncementOnly, before.AnnouncementOnly, after.AnnouncementOnly)
+	diff("slowModeSeconds", before.SlowModeSeconds != after.SlowModeSeconds, before.SlowModeSeconds, after.SlowModeSeconds)
+
+	return oldValues, newValues
+}
+
+func roleName(room *domain.Room, userID string) string {
+	if room.IsAdmin(userID) {
+		return "admin"
+	}
+	return "member"
+}
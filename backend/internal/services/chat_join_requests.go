@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+
+	"github.com/napat2224/socket-programming-chat-app/internal/domain"
+)
+
+type JoinRequestResult struct {
+	Request *domain.JoinRequest
+	Created bool
+}
+
+type JoinRequestDetail struct {
+	*domain.JoinRequest
+	Name    string             `json:"name"`
+	Profile domain.ProfileType `json:"profile"`
+}
+
+func (s *ChatService) ListJoinRequests(ctx context.Context, roomID, actorID string) ([]*JoinRequestDetail, error) {
+	room, err := s.getRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if !room.IsAdmin(actorID) {
+		return nil, ErrForbidden
+	}
+
+	requests, err := s.joinRequestRepo.FindPendingByRoomID(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]string, 0, len(requests))
+	for _, r := range requests {
+		userIDs = append(userIDs, r.UserID)
+	}
+	users, err := s.userRepo.GetUsersByIDs(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	userMap := make(map[string]*domain.User, len(users))
+	for _, u := range users {
+		userMap[u.UserID] = u
+	}
+
+	result := make([]*JoinRequestDetail, 0, len(requests))
+	for _, r := range requests {
+		detail := &JoinRequestDetail{JoinRequest: r, Name: "Unknown User", Profile: domain.Profile1}
+		if u := userMap[r.UserID]; u != nil {
+			detail.Name = u.Name
+			detail.Profile = u.Profile
+		}
+		result = append(result, detail)
+	}
+	return result, nil
+}
+
+// DecideJoinRequest approves or rejects a pending request. Approval adds the
+// requester to the room. The requester's user record is returned so callers
+// can announce the new member.
+func (s *ChatService) DecideJoinRequest(ctx context.Context, roomID, requestID, actorID string, approve bool) (*domain.JoinRequest, *domain.User, error) {
+	room, err := s.getRoom(ctx, roomID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !room.IsAdmin(actorID) {
+		return nil, nil, ErrForbidden
+	}
+
+	request, err := s.joinRequestRepo.FindByID(ctx, requestID)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil, ErrRequestClosed
+		}
+		return nil, nil, err
+	}
+	if request.RoomID != roomID {
+		return nil, nil, ErrRequestClosed
+	}
+	if approve && room.IsBanned(request.UserID) {
+		return nil, nil, ErrUserBanned
+	}
+	if approve && room.IsArchived() {
+		return nil, nil, ErrRoomArchived
+	}
+
+	status := domain.JoinRequestRejected
+	if approve {
+		status = domain.JoinRequestApproved
+	}
+	request, err = s.joinRequestRepo.Decide(ctx, requestID, status, actorID)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil, ErrRequestClosed
+		}
+		return nil, nil, err
+	}
+
+	if approve {
+		if err := s.roomRepo.JoinRoom(ctx, roomID, request.UserID); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	action := domain.AuditJoinRejected
+	if approve {
+		action = domain.AuditJoinApproved
+	}
+	recordAudit(ctx, s.auditRepo, domain.AuditEntry{
+		RoomID:   roomID,
+		ActorID:  actorID,
+		Action:   action,
+		TargetID: request.UserID,
+		Before:   map[string]any{"status": domain.JoinRequestPending},
+		After:    map[string]any{"status": request.Status},
+	})
+
+	user, err := s.userRepo.FindById(ctx, request.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user == nil {
+		user = &domain.User{UserID: request.UserID, Name: "Unknown User", Profile: domain.Profile1}
+	}
+	return request, user, nil
+}
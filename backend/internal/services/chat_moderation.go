@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/napat2224/socket-programming-chat-app/internal/domain"
+)
+
+// authorizeModeration loads the room and checks that actorID may moderate
+// targetID. Admins can act on regular members, only the creator can act on
+// other admins, and nobody can act on the creator or on themselves.
+func (s *ChatService) authorizeModeration(ctx context.Context, roomID, actorID, targetID string) (*domain.Room, error) {
+	room, err := s.getRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if !room.IsAdmin(actorID) {
+		return nil, ErrForbidden
+	}
+	if targetID == "" || targetID == actorID || targetID == room.CreatorID {
+		return nil, ErrInvalidTarget
+	}
+	if room.IsAdmin(targetID) && actorID != room.CreatorID {
+		return nil, ErrForbidden
+	}
+	return room, nil
+}
+
+func (s *ChatService) auditModeration(ctx context.Context, roomID, actorID, targetID string, action domain.AuditAction, before, after map[string]any) {
+	recordAudit(ctx, s.auditRepo, domain.AuditEntry{
+		RoomID:   roomID,
+		ActorID:  actorID,
+		Action:   action,
+		TargetID: targetID,
+		Before:   before,
+		After:    after,
+	})
+}
+
+func (s *ChatService) KickMember(ctx context.Context, roomID, actorID, targetID string) error {
+	room, err := s.authorizeModeration(ctx, roomID, actorID, targetID)
+	if err != nil {
+		return err
+	}
+	if !room.IsMember(targetID) {
+		return ErrInvalidTarget
+	}
+	if err := s.roomRepo.RemoveMember(ctx, roomID, targetID); err != nil {
+		return err
+	}
+
+	s.auditModeration(ctx, roomID, actorID, targetID, domain.AuditMemberKicked,
+		map[string]any{"member": true}, map[string]any{"member": false})
+	return nil
+}
+
+func (s *ChatService) BanMember(ctx context.Context, roomID, actorID, targetID string) error {
+	room, err := s.authorizeModeration(ctx, roomID, actorID, targetID)
+	if err != nil {
+		return err
+	}
+	if err := s.roomRepo.BanMember(ctx, roomID, targetID); err != nil {
+		return err
+	}
+
+	s.auditModeration(ctx, roomID, actorID, targetID, domain.AuditMemberBanned,
+		map[string]any{"banned": room.IsBanned(targetID), "member": room.IsMember(targetID)},
+		map[string]any{"banned": true, "member": false})
+	return nil
+}
+
+func (s *ChatService) UnbanMember(ctx context.Context, roomID, actorID, targetID string) error {
+	room, err := s.authorizeModeration(ctx, roomID, actorID, targetID)
+	if err != nil {
+		return err
+	}
+	if err := s.roomRepo.UnbanMember(ctx, roomID, targetID); err != nil {
+		return err
+	}
+
+	s.auditModeration(ctx, roomID, actorID, targetID, domain.AuditMemberUnbanned,
+		map[string]any{"banned": room.IsBanned(targetID)}, map[string]any{"banned": false})
+	return nil
+}
+
+// MuteMember silences the target until now+duration and returns the expiry.
+func (s *ChatService) MuteMember(ctx context.Context, roomID, actorID, targetID string, duration time.Duration) (time.Time, error) {
+	if duration <= 0 {
+		return time.Time{}, ErrInvalidDuration
+	}
+	room, err := s.authorizeModeration(ctx, roomID, actorID, targetID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !room.IsMember(targetID) {
+		return time.Time{}, ErrInvalidTarget
+	}
+
+	until := time.Now().Add(duration)
+	if err := s.roomRepo.MuteMember(ctx, roomID, targetID, until); err != nil {
+		return time.Time{}, err
+	}
+
+	s.auditModeration(ctx, roomID, actorID, targetID, domain.AuditMemberMuted,
+		mutedValue(room, targetID), map[string]any{"mutedUntil": until})
+	return until, nil
+}
+
+func (s *ChatService) UnmuteMember(ctx context.Context, roomID, actorID, targetID string) error {
+	room, err := s.authorizeModeration(ctx, roomID, actorID, targetID)
+	if err != nil {
+		return err
+	}
+	if err := s.roomRepo.UnmuteMember(ctx, roomID, targetID); err != nil {
+		return err
+	}
+
+	s.auditModeration(ctx, roomID, actorID, targetID, domain.AuditMemberUnmuted,
+		mutedValue(room, targetID), map[string]any{"mutedUntil": nil})
+	return nil
+}
+
+// SetMemberRole promotes a member to admin or demotes them. Only the room
+// creator can change roles.
+func (s *ChatService) SetMemberRole(ctx context.Context, roomID, actorID, targetID string, admin bool) error {
+	room, err := s.authorizeModeration(ctx, roomID, actorID, targetID)
+	if err != nil {
+		return err
+	}
+	if actorID != room.CreatorID {
+		return ErrForbidden
+	}
+	if !room.IsMember(targetID) {
+		return ErrInvalidTarget
+	}
+	if err := s.roomRepo.SetAdmin(ctx, roomID, targetID, admin); err != nil {
+		return err
+	}
+
+	after := "member"
+	if admin {
+		after = "admin"
+	}
+	s.auditModeration(ctx, roomID, actorID, targetID, domain.AuditRoleChanged,
+		map[string]any{"role": roleName(room, targetID)}, map[string]any{"role": after})
+	return nil
+}
+
+func mutedValue(room *domain.Room, userID string) map[string]any {
+	if until, ok := room.MutedAt(userID, time.Now()); ok {
+		return map[string]any{"mutedUntil": until}
+	}
+	return map[string]any{"mutedUntil": nil}
+}
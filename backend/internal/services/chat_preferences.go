@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/napat2224/socket-programming-chat-app/internal/domain"
+)
+
+const (
+	maxNicknameLength = 64
+	maxMuteSeconds    = 365 * 24 * 60 * 60
+)
+
+// UserRoom is a room as seen by one user, with their preferences merged in.
+type UserRoom struct {
+	Room       *domain.Room
+	Preference *domain.RoomPreference
+}
+
+// GetRoomPreferences returns the user's preferences for each room id. Rooms
+// without stored preferences get the zero-value defaults.
+func (s *ChatService) GetRoomPreferences(ctx context.Context, userID string, roomIDs []string) (map[string]*domain.RoomPreference, error) {
+	prefs, err := s.preferenceRepo.FindByUser(ctx, userID, roomIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range roomIDs {
+		if prefs[id] == nil {
+			prefs[id] = &domain.RoomPreference{UserID: userID, RoomID: id}
+		}
+	}
+	return prefs, nil
+}
+
+func (s *ChatService) UpdateRoomPreference(ctx context.Context, userID, roomID string, update domain.RoomPreferenceUpdate) (*domain.RoomPreference, error) {
+	room, err := s.getRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if !room.IsMember(userID) {
+		return nil, ErrForbidden
+	}
+
+	if update.MuteSeconds != nil && (*update.MuteSeconds < 0 || *update.MuteSeconds > maxMuteSeconds) {
+		return nil, fmt.Errorf("%w: muteSeconds must be between 0 and %d", ErrInvalidInput, maxMuteSeconds)
+	}
+	if update.Nickname != nil {
+		nickname := strings.TrimSpace(*update.Nickname)
+		if nickname != "" && !isDirectMessage(room) {
+			return nil, fmt.Errorf("%w: nicknames can only be set on direct messages", ErrInvalidInput)
+		}
+		if utf8.RuneCountInString(nickname) > maxNicknameLength {
+			return nil, fmt.Errorf("%w: nickname must be at most %d characters", ErrInvalidInput, maxNicknameLength)
+		}
+		update.Nickname = &nickname
+	}
+
+	return s.preferenceRepo.Update(ctx, userID, roomID, update, time.Now())
+}
+
+// ListUserRooms returns the rooms the user belongs to, favourites first and
+// otherwise most recently active first. Hidden rooms are dropped unless
+// includeHidden is set.
+func (s *ChatService) ListUserRooms(ctx context.Context, userID string, includeHidden bool) ([]*UserRoom, error) {
+	rooms, err := s.roomRepo.GetChatRoomsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	roomIDs := make([]string, 0, len(rooms))
+	for _, r := range rooms {
+		roomIDs = append(roomIDs, r.ID)
+	}
+	prefs, err := s.GetRoomPreferences(ctx, userID, roomIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*UserRoom, 0, len(rooms))
+	for _, r := range rooms {
+		pref := prefs[r.ID]
+		if pref.Hidden && !includeHidden {
+			continue
+		}
+		result = append(result, &UserRoom{Room: r, Preference: pref})
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Preference.Favourite && !result[j].Preference.Favourite
+	})
+	return result, nil
+}
+
+func isDirectMessage(room *domain.Room) bool {
+	return !room.IsPublic && len(room.MemberIDs) == 2
+}
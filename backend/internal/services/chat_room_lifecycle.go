@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/napat2224/socket-programming-chat-app/internal/domain"
+)
+
+func (s *ChatService) ownedRoom(ctx context.Context, roomID, actorID string) (*domain.Room, error) {
+	room, err := s.getRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if room.CreatorID != actorID {
+		return nil, ErrForbidden
+	}
+	return room, nil
+}
+
+// SetRoomArchived archives or restores a room. Archived rooms are read-only
+// and hidden from the public directory. Only the owner may do this.
+func (s *ChatService) SetRoomArchived(ctx context.Context, roomID, actorID string, archived bool) (*domain.Room, error) {
+	room, err := s.ownedRoom(ctx, roomID, actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	var archivedAt *time.Time
+	if archived {
+		now := time.Now()
+		archivedAt = &now
+	}
+	if err := s.roomRepo.SetArchived(ctx, roomID, archivedAt); err != nil {
+		return nil, err
+	}
+
+	action := domain.AuditRoomUnarchived
+	if archived {
+		action = domain.AuditRoomArchived
+	}
+	recordAudit(ctx, s.auditRepo, domain.AuditEntry{
+		RoomID:  roomID,
+		ActorID: actorID,
+		Action:  action,
+		Before:  map[string]any{"archived": room.IsArchived()},
+		After:   map[string]any{"archived": archived},
+	})
+
+	room.ArchivedAt = archivedAt
+	return room, nil
+}
+
+// DeleteRoom permanently removes the room and everything stored under it.
+// Child data goes first: if any of it fails to delete, the room is kept and
+// the error returned, so the owner can retry instead of leaving orphans
+// behind a room that no longer exists.
+func (s *ChatService) DeleteRoom(ctx context.Context, roomID, actorID string) (*domain.Room, error) {
+	room, err := s.ownedRoom(ctx, roomID, actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := errors.Join(
+		s.messageRepo.DeleteByRoomID(ctx, roomID),
+		s.inviteRepo.DeleteByRoomID(ctx, roomID),
+		s.joinRequestRepo.DeleteByRoomID(ctx, roomID),
+		s.preferenceRepo.DeleteByRoomID(ctx, roomID),
+	); err != nil {
+		return nil, fmt.Errorf("delete room data: %w", err)
+	}
+
+	if err := s.roomRepo.DeleteRoom(ctx, roomID); err != nil {
+		if isNotFound(err) {
+			return nil, ErrRoomNotFound
+		}
+		return nil, err
+	}
+	return room, nil
+}
@@ -2,24 +2,35 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/url"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/napat2224/socket-programming-chat-app/internal/domain"
 	"github.com/napat2224/socket-programming-chat-app/internal/repository"
 )
 
 type ChatService struct {
-	roomRepo    *repository.RoomRepository
-	messageRepo *repository.MessageRepository
-	userRepo    *repository.UserRepository
+	roomRepo        *repository.RoomRepository
+	messageRepo     *repository.MessageRepository
+	userRepo        *repository.UserRepository
+	joinRequestRepo *repository.JoinRequestRepository
+	inviteRepo      *repository.InviteRepository
+	preferenceRepo  *repository.RoomPreferenceRepository
+	auditRepo       *repository.AuditRepository
 }
 
 type RoomMember struct {
 	ID      string             `json:"id"`
 	Name    string             `json:"name"`
 	Profile domain.ProfileType `json:"profile"`
+	Online  bool               `json:"online"`
+
+	LastSeenAt *time.Time `json:"lastSeenAt,omitempty"`
 }
 
 type RoomDetail struct {
@@ -32,11 +43,19 @@ func NewChatService(
 	roomRepo *repository.RoomRepository,
 	messageRepo *repository.MessageRepository,
 	userRepo *repository.UserRepository,
+	joinRequestRepo *repository.JoinRequestRepository,
+	inviteRepo *repository.InviteRepository,
+	preferenceRepo *repository.RoomPreferenceRepository,
+	auditRepo *repository.AuditRepository,
 ) *ChatService {
 	return &ChatService{
-		roomRepo:    roomRepo,
-		messageRepo: messageRepo,
-		userRepo:    userRepo,
+		roomRepo:        roomRepo,
+		messageRepo:     messageRepo,
+		userRepo:        userRepo,
+		joinRequestRepo: joinRequestRepo,
+		inviteRepo:      inviteRepo,
+		preferenceRepo:  preferenceRepo,
+		auditRepo:       auditRepo,
 	}
 }
 
@@ -46,25 +65,59 @@ func (s *ChatService) CreateRoom(
 	memberIDs []string,
 	roomName string,
 	background domain.BackgroundColor,
-	isPublic bool,
+	visibility domain.RoomVisibility,
 ) (*domain.Room, error) {
-	if len(memberIDs) == 0 {
-		memberIDs = []string{creatorID}
+	if !visibility.IsValid() {
+		visibility = domain.VisibilityPublic
+	}
+
+	memberIDs, err := s.resolveMembers(ctx, creatorID, memberIDs)
+	if err != nil {
+		return nil, err
 	}
 
 	room := &domain.Room{
-		ID:              "",
-		CreatorID:       creatorID,
-		MemberIDs:       memberIDs,
-		RoomName:        roomName,
-		BackgroundColor: background,
-		LastMessageSent: time.Time{},
-		IsPublic:        isPublic,
+		ID:               "",
+		CreatorID:        creatorID,
+		MemberIDs:        memberIDs,
+		RoomName:         roomName,
+		BackgroundColor:  background,
+		LastMessageSent:  time.Time{},
+		IsPublic:         visibility != domain.VisibilityPrivate,
+		RequiresApproval: visibility == domain.VisibilityRestricted,
 	}
 
 	return s.roomRepo.SaveRoom(ctx, room)
 }
 
+// resolveMembers returns the creator followed by the requested members that
+// exist, with duplicates and unknown user ids dropped.
+func (s *ChatService) resolveMembers(ctx context.Context, creatorID string, memberIDs []string) ([]string, error) {
+	result := []string{creatorID}
+	if len(memberIDs) == 0 {
+		return result, nil
+	}
+
+	users, err := s.userRepo.GetUsersByIDs(ctx, memberIDs)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(users))
+	for _, u := range users {
+		known[u.UserID] = true
+	}
+
+	seen := map[string]bool{creatorID: true}
+	for _, id := range memberIDs {
+		if seen[id] || !known[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	return result, nil
+}
+
 func (s *ChatService) SendTextMessage(
 	ctx context.Context,
 	roomID string,
@@ -72,9 +125,20 @@ func (s *ChatService) SendTextMessage(
 	content string,
 	replyTo string,
 ) (*domain.Message, error) {
-	if exists, err := s.roomRepo.RoomExists(ctx, roomID); err != nil {
+	room, err := s.getRoom(ctx, roomID)
+	if err != nil {
 		return nil, err
-	} else if !exists {
+	}
+	if err := checkParticipant(room, senderID); err != nil {
+		return nil, err
+	}
+	if room.IsArchived() {
+		return nil, ErrRoomArchived
+	}
+	if until, muted := room.MutedAt(senderID, time.Now()); muted {
+		return nil, &MutedError{Until: until}
+	}
+	if err := s.checkPostingRules(ctx, room, senderID); err != nil {
 		return nil, err
 	}
 
@@ -88,7 +152,38 @@ func (s *ChatService) SendTextMessage(
 		CreatedAt: time.Now(),
 	}
 
-	return s.messageRepo.SaveMessage(ctx, msg)
+	saved, err := s.messageRepo.SaveMessage(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.roomRepo.TouchLastMessage(ctx, roomID, saved.CreatedAt); err != nil {
+		log.Println("failed to update last message time:", err)
+	}
+	return saved, nil
+}
+
+// checkPostingRules enforces announcement and slow mode. Admins are exempt
+// from both.
+func (s *ChatService) checkPostingRules(ctx context.Context, room *domain.Room, senderID string) error {
+	if room.IsAdmin(senderID) {
+		return nil
+	}
+	if room.AnnouncementOnly {
+		return ErrAnnouncement
+	}
+	if room.SlowModeSeconds <= 0 {
+		return nil
+	}
+
+	last, err := s.messageRepo.FindLastBySender(ctx, room.ID, senderID)
+	if err != nil || last == nil {
+		return err
+	}
+	interval := time.Duration(room.SlowModeSeconds) * time.Second
+	if wait := interval - time.Since(last.CreatedAt); wait > 0 {
+		return &SlowModeError{RetryAfter: wait}
+	}
+	return nil
 }
 
 func (s *ChatService) GetRoomMessages(
@@ -167,10 +262,81 @@ func (s *ChatService) GetUserRooms(
 	return s.roomRepo.GetChatRoomsByUserID(ctx, userID)
 }
 
+// PresenceAudience returns the users who share at least one room with the
+// user and may therefore see their presence.
+func (s *ChatService) PresenceAudience(ctx context.Context, userID string) ([]string, error) {
+	rooms, err := s.roomRepo.GetChatRoomsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]struct{}{userID: {}}
+	audience := make([]string, 0)
+	for _, room := range rooms {
+		for _, memberID := range room.MemberIDs {
+			if _, ok := seen[memberID]; ok {
+				continue
+			}
+			seen[memberID] = struct{}{}
+			audience = append(audience, memberID)
+		}
+	}
+	return audience, nil
+}
+
+// SharesRoom reports whether viewerID may see userID's presence: they are
+// the same user or members of a common room.
+func (s *ChatService) SharesRoom(ctx context.Context, viewerID string, userID string) (bool, error) {
+	if viewerID == userID {
+		return true, nil
+	}
+	rooms, err := s.roomRepo.GetChatRoomsByUserID(ctx, viewerID)
+	if err != nil {
+		return false, err
+	}
+	for _, room := range rooms {
+		if room.IsMember(userID) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 func (s *ChatService) GetAllPublicRooms(ctx context.Context) ([]*domain.Room, error) {
 	return s.roomRepo.GetAllPublicRooms(ctx)
 }
 
+const (
+	defaultDirectoryPageSize = 50
+	maxDirectoryPageSize     = 100
+)
+
+// SearchPublicRooms returns a page of the public room directory and the
+// cursor of the next page.
+func (s *ChatService) SearchPublicRooms(ctx context.Context, query domain.RoomDirectoryQuery) ([]*domain.Room, string, error) {
+	query.Search = strings.TrimSpace(query.Search)
+	if !query.Sort.IsValid() {
+		query.Sort = domain.RoomSortCreated
+	}
+	if query.Limit <= 0 {
+		query.Limit = defaultDirectoryPageSize
+	}
+	if query.Limit > maxDirectoryPageSize {
+		query.Limit = maxDirectoryPageSize
+	}
+	tags, err := normalizeTags(query.Tags)
+	if err != nil {
+		return nil, "", err
+	}
+	query.Tags = tags
+
+	rooms, next, err := s.roomRepo.SearchPublicRooms(ctx, query)
+	if errors.Is(err, repository.ErrInvalidCursor) {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	return rooms, next, err
+}
+
 func (s *ChatService) GetPrivateRoomByTargetID(ctx context.Context, currentUserID string, targetID string) (*domain.Room, error) {
 	room, _ := s.roomRepo.GetPrivateRoomByTargetID(ctx, currentUserID, targetID)
 	if room == nil {
@@ -221,43 +387,131 @@ func (s *ChatService) GetPrivateRoomByTargetID(ctx context.Context, currentUserI
 func (s *ChatService) AddReaction(
 	ctx context.Context,
 	messageID string,
+	reactorID string,
 	reaction domain.ReactionType,
 ) (*domain.Message, error) {
+	msg, err := s.messageRepo.FindByID(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	room, err := s.getRoom(ctx, msg.RoomID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkParticipant(room, reactorID); err != nil {
+		return nil, err
+	}
+	if room.IsArchived() {
+		return nil, ErrRoomArchived
+	}
 	return s.messageRepo.AddReaction(ctx, messageID, reaction)
 }
 
-func (s *ChatService) JoinRoom(ctx context.Context, roomID string, userID string) error {
-	return s.roomRepo.JoinRoom(ctx, roomID, userID)
+// JoinRoom adds the user to the room. For restricted rooms a non-member gets
+// a pending join request instead; it is returned with created set when this
+// call opened it. Private rooms can only be joined by existing members.
+func (s *ChatService) JoinRoom(ctx context.Context, roomID string, userID string) (*domain.Room, *JoinRequestResult, error) {
+	room, err := s.getRoom(ctx, roomID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if room.IsBanned(userID) {
+		return nil, nil, ErrUserBanned
+	}
+	if room.IsArchived() {
+		return nil, nil, ErrRoomArchived
+	}
+
+	if !room.IsMember(userID) {
+		switch room.Visibility() {
+		case domain.VisibilityPrivate:
+			return nil, nil, ErrForbidden
+		case domain.VisibilityRestricted:
+			request, created, err := s.joinRequestRepo.CreatePending(ctx, roomID, userID)
+			if err != nil {
+				return nil, nil, err
+			}
+			return room, &JoinRequestResult{Request: request, Created: created}, nil
+		}
+	}
+
+	if err := s.roomRepo.JoinRoom(ctx, roomID, userID); err != nil {
+		return nil, nil, err
+	}
+	return room, nil, nil
 }
 
+// getRoom loads a room and maps a missing document to ErrRoomNotFound.
+func (s *ChatService) getRoom(ctx context.Context, roomID string) (*domain.Room, error) {
+	room, err := s.roomRepo.GetChatRoomsByRoomID(ctx, roomID)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, ErrRoomNotFound
+		}
+		return nil, err
+	}
+	return room, nil
+}
+
+// CanSubscribe checks that the user may receive a room's live events without
+// changing their membership.
+func (s *ChatService) CanSubscribe(ctx context.Context, roomID string, userID string) error {
+	room, err := s.getRoom(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	return checkParticipant(room, userID)
+}
+
+// checkParticipant allows only current members to post, react or follow a
+// room, so kicked and banned users lose access even if they know its id.
+func checkParticipant(room *domain.Room, userID string) error {
+	if room.IsBanned(userID) {
+		return ErrUserBanned
+	}
+	if !room.IsMember(userID) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// GetChatRoomByRoomID returns a room and its members, and whether the viewer
+// is a member. Only members may see other members' presence, so last-seen
+// times are filled in for them alone.
 func (s *ChatService) GetChatRoomByRoomID(
 	ctx context.Context,
 	roomID string,
-) (*RoomDetail, error) {
+	viewerID string,
+) (*RoomDetail, bool, error) {
 	room, err := s.roomRepo.GetChatRoomsByRoomID(ctx, roomID)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
 	users, err := s.userRepo.GetUsersByIDs(ctx, room.MemberIDs)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
+	showPresence := room.IsMember(viewerID)
 	members := make([]RoomMember, 0, len(users))
 	for _, u := range users {
-		members = append(members, RoomMember{
+		member := RoomMember{
 			ID:      u.UserID,
 			Name:    u.Name,
 			Profile: u.Profile,
-		})
+		}
+		if showPresence {
+			member.LastSeenAt = u.VisibleLastSeen()
+		}
+		members = append(members, member)
 	}
 
 	return &RoomDetail{
 		RoomID:   room.ID,
 		RoomName: room.RoomName,
 		Members:  members,
-	}, nil
+	}, showPresence, nil
 }
 
 type MessageWithUserDetail struct {
@@ -273,31 +527,129 @@ type MessageWithUserDetail struct {
 	SenderProfile domain.ProfileType `json:"senderProfile"`
 }
 
-type UpdateBackgroundResponse struct {
-	BackgroundColor string `json:"backgroundColor"`
-}
+const (
+	maxRoomNameLength    = 64
+	maxRoomTopicLength   = 120
+	maxRoomDescLength    = 1000
+	maxRoomAvatarURLSize = 512
+	maxSlowModeSeconds   = 6 * 60 * 60
+)
 
-func (s *ChatService) UpdateBackgroundRoom(ctx context.Context, roomID string, background string) (*UpdateBackgroundResponse, error) {
-	room, err := s.roomRepo.GetChatRoomsByRoomID(ctx, roomID)
+// UpdateRoomSettings validates and applies a partial metadata update. Any
+// member may change the theme; everything else requires a room admin.
+func (s *ChatService) UpdateRoomSettings(ctx context.Context, roomID, actorID string, settings domain.RoomSettings) (*domain.Room, error) {
+	if settings.IsEmpty() {
+		return nil, fmt.Errorf("%w: no settings to update", ErrInvalidInput)
+	}
+
+	room, err := s.getRoom(ctx, roomID)
 	if err != nil {
 		return nil, err
 	}
-	if room == nil {
-		return nil, fmt.Errorf("room not found")
+	if !room.IsMember(actorID) && !room.IsAdmin(actorID) {
+		return nil, ErrForbidden
+	}
+	if !settings.OnlyTheme() && !room.IsAdmin(actorID) {
+		return nil, ErrForbidden
+	}
+	if room.IsArchived() {
+		return nil, ErrRoomArchived
 	}
 
-	room.BackgroundColor = BackgroundColor(background)
+	if err := normalizeRoomSettings(&settings); err != nil {
+		return nil, err
+	}
 
-	_, err = s.roomRepo.UpdateRoom(ctx, roomID, background)
+	updated, err := s.roomRepo.UpdateRoom(ctx, roomID, settings)
 	if err != nil {
 		return nil, err
 	}
 
-	return &UpdateBackgroundResponse{
-		BackgroundColor: background,
-	}, nil
+	if before, after := roomSettingsDiff(room, updated); len(after) > 0 {
+		recordAudit(ctx, s.auditRepo, domain.AuditEntry{
+			RoomID:  roomID,
+			ActorID: actorID,
+			Action:  domain.AuditRoomUpdated,
+			Before:  before,
+			After:   after,
+		})
+	}
+	return updated, nil
+}
+
+func normalizeRoomSettings(settings *domain.RoomSettings) error {
+	if settings.RoomName != nil {
+		name := strings.TrimSpace(*settings.RoomName)
+		if name == "" || utf8.RuneCountInString(name) > maxRoomNameLength {
+			return fmt.Errorf("%w: room name must be 1-%d characters", ErrInvalidInput, maxRoomNameLength)
+		}
+		settings.RoomName = &name
+	}
+	if settings.Topic != nil {
+		topic := strings.TrimSpace(*settings.Topic)
+		if utf8.RuneCountInString(topic) > maxRoomTopicLength {
+			return fmt.Errorf("%w: topic must be at most %d characters", ErrInvalidInput, maxRoomTopicLength)
+		}
+		settings.Topic = &topic
+	}
+	if settings.Description != nil {
+		desc := strings.TrimSpace(*settings.Description)
+		if utf8.RuneCountInString(desc) > maxRoomDescLength {
+			return fmt.Errorf("%w: description must be at most %d characters", ErrInvalidInput, maxRoomDescLength)
+		}
+		settings.Description = &desc
+	}
+	if settings.AvatarURL != nil {
+		avatar := strings.TrimSpace(*settings.AvatarURL)
+		if len(avatar) > maxRoomAvatarURLSize {
+			return fmt.Errorf("%w: avatar url is too long", ErrInvalidInput)
+		}
+		if avatar != "" {
+			u, err := url.Parse(avatar)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("%w: avatar must be an http(s) url", ErrInvalidInput)
+			}
+		}
+		settings.AvatarURL = &avatar
+	}
+	if settings.BackgroundColor != nil && !settings.BackgroundColor.IsValid() {
+		return fmt.Errorf("%w: unknown background color %q", ErrInvalidInput, *settings.BackgroundColor)
+	}
+	if settings.SlowModeSeconds != nil && (*settings.SlowModeSeconds < 0 || *settings.SlowModeSeconds > maxSlowModeSeconds) {
+		return fmt.Errorf("%w: slow mode must be between 0 and %d seconds", ErrInvalidInput, maxSlowModeSeconds)
+	}
+	if settings.Tags != nil {
+		tags, err := normalizeTags(*settings.Tags)
+		if err != nil {
+			return err
+		}
+		settings.Tags = &tags
+	}
+	return nil
 }
 
-func BackgroundColor(background string) domain.BackgroundColor {
-	return domain.BackgroundColor(background)
+const (
+	maxRoomTags      = 5
+	maxRoomTagLength = 24
+)
+
+// normalizeTags lower-cases, trims and de-duplicates tags.
+func normalizeTags(tags []string) ([]string, error) {
+	result := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" || seen[t] {
+			continue
+		}
+		if utf8.RuneCountInString(t) > maxRoomTagLength {
+			return nil, fmt.Errorf("%w: tags must be at most %d characters", ErrInvalidInput, maxRoomTagLength)
+		}
+		seen[t] = true
+		result = append(result, t)
+	}
+	if len(result) > maxRoomTags {
+		return nil, fmt.Errorf("%w: at most %d tags are allowed", ErrInvalidInput, maxRoomTags)
+	}
+	return result, nil
 }
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/napat2224/socket-programming-chat-app/internal/domain"
+)
+
+// SendTextMessage, AddReaction and CanSubscribe all gate on checkParticipant
+// right after loading the room.
+func TestCheckParticipantRejectsKickedAndBannedUsers(t *testing.T) {
+	room := &domain.Room{
+		ID:        "r",
+		CreatorID: "owner",
+		MemberIDs: []string{"owner", "member"},
+		// "kicked" was removed from MemberIDs; a ban also removes membership.
+		BannedIDs: []string{"banned"},
+	}
+
+	cases := []struct {
+		userID string
+		want   error
+	}{
+		{"owner", nil},
+		{"member", nil},
+		{"kicked", ErrForbidden},
+		{"banned", ErrUserBanned},
+		{"", ErrForbidden},
+	}
+	for _, tc := range cases {
+		if err := checkParticipant(room, tc.userID); !errors.Is(err, tc.want) {
+			t.Errorf("checkParticipant(%q) = %v, want %v", tc.userID, err, tc.want)
+		}
+	}
+
+	// A ban stands even if the user is somehow still listed as a member.
+	room.MemberIDs = append(room.MemberIDs, "banned")
+	if err := checkParticipant(room, "banned"); !errors.Is(err, ErrUserBanned) {
+		t.Errorf("banned member: got %v, want ErrUserBanned", err)
+	}
+}
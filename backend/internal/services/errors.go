@@ -0,0 +1,59 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrRoomNotFound    = errors.New("room not found")
+	ErrForbidden       = errors.New("permission denied")
+	ErrInvalidTarget   = errors.New("invalid target user")
+	ErrInvalidDuration = errors.New("duration must be positive")
+	ErrUserBanned      = errors.New("user is banned from this room")
+	ErrUserMuted       = errors.New("user is muted in this room")
+	ErrInviteInvalid   = errors.New("invite is invalid, expired or used up")
+	ErrRequestClosed   = errors.New("join request not found or already decided")
+	ErrInvalidInput    = errors.New("invalid input")
+	ErrRoomArchived    = errors.New("room is archived")
+	ErrAnnouncement    = errors.New("only admins can post in this room")
+	ErrSlowMode        = errors.New("slow mode is enabled in this room")
+)
+
+// MutedError is returned when a muted user tries to post. It matches
+// ErrUserMuted with errors.Is and carries the mute expiry.
+type MutedError struct {
+	Until time.Time
+}
+
+func (e *MutedError) Error() string {
+	return fmt.Sprintf("user is muted until %s", e.Until.Format(time.RFC3339))
+}
+
+func (e *MutedError) Is(target error) bool {
+	return target == ErrUserMuted
+}
+
+// SlowModeError is returned when a member posts again before the room's
+// slow-mode interval has passed. It matches ErrSlowMode with errors.Is.
+type SlowModeError struct {
+	RetryAfter time.Duration
+}
+
+func (e *SlowModeError) Error() string {
+	return fmt.Sprintf("slow mode: retry in %s", e.RetryAfter.Round(time.Second))
+}
+
+func (e *SlowModeError) Is(target error) bool {
+	return target == ErrSlowMode
+}
+
+// isNotFound reports whether a repository error means the document is missing
+// or the id could not address one.
+func isNotFound(err error) bool {
+	return errors.Is(err, mongo.ErrNoDocuments) || errors.Is(err, primitive.ErrInvalidHex)
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+
+	"github.com/napat2224/socket-programming-chat-app/internal/domain"
+	"github.com/napat2224/socket-programming-chat-app/internal/repository"
+)
+
+type InviteService struct {
+	inviteRepo *repository.InviteRepository
+	roomRepo   *repository.RoomRepository
+	auditRepo  *repository.AuditRepository
+}
+
+func NewInviteService(inviteRepo *repository.InviteRepository, roomRepo *repository.RoomRepository, auditRepo *repository.AuditRepository) *InviteService {
+	return &InviteService{
+		inviteRepo: inviteRepo,
+		roomRepo:   roomRepo,
+		auditRepo:  auditRepo,
+	}
+}
+
+func (s *InviteService) adminRoom(ctx context.Context, roomID string, userID string) (*domain.Room, error) {
+	room, err := s.roomRepo.GetChatRoomsByRoomID(ctx, roomID)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, ErrRoomNotFound
+		}
+		return nil, err
+	}
+	if !room.IsAdmin(userID) {
+		return nil, ErrForbidden
+	}
+	return room, nil
+}
+
+// CreateInvite issues a new token for the room. A zero ttl never expires and
+// a zero maxUses allows unlimited redemptions.
+func (s *InviteService) CreateInvite(ctx context.Context, roomID, creatorID string, ttl time.Duration, maxUses int) (*domain.RoomInvite, error) {
+	if ttl < 0 || maxUses < 0 {
+		return nil, ErrInviteInvalid
+	}
+	if _, err := s.adminRoom(ctx, roomID, creatorID); err != nil {
+		return nil, err
+	}
+
+	token, err := newInviteToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	invite := &domain.RoomInvite{
+		Token:     token,
+		RoomID:    roomID,
+		CreatedBy: creatorID,
+		MaxUses:   maxUses,
+		CreatedAt: now,
+	}
+	if ttl > 0 {
+		expiresAt := now.Add(ttl)
+		invite.ExpiresAt = &expiresAt
+	}
+
+	saved, err := s.inviteRepo.SaveInvite(ctx, invite)
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(ctx, s.auditRepo, domain.AuditEntry{
+		RoomID:  roomID,
+		ActorID: creatorID,
+		Action:  domain.AuditInviteCreated,
+		After: map[string]any{
+			"token":     saved.Token,
+			"expiresAt": saved.ExpiresAt,
+			"maxUses":   saved.MaxUses,
+		},
+	})
+	return saved, nil
+}
+
+func (s *InviteService) ListInvites(ctx context.Context, roomID, userID string) ([]*domain.RoomInvite, error) {
+	if _, err := s.adminRoom(ctx, roomID, userID); err != nil {
+		return nil, err
+	}
+	return s.inviteRepo.FindByRoomID(ctx, roomID)
+}
+
+func (s *InviteService) RevokeInvite(ctx context.Context, roomID, userID, token string) error {
+	if _, err := s.adminRoom(ctx, roomID, userID); err != nil {
+		return err
+	}
+
+	invite, err := s.inviteRepo.FindByToken(ctx, token)
+	if err != nil {
+		if isNotFound(err) {
+			return ErrInviteInvalid
+		}
+		return err
+	}
+	if invite.RoomID != roomID {
+		return ErrInviteInvalid
+	}
+	if err := s.inviteRepo.Revoke(ctx, token); err != nil {
+		return err
+	}
+
+	recordAudit(ctx, s.auditRepo, domain.AuditEntry{
+		RoomID:  roomID,
+		ActorID: userID,
+		Action:  domain.AuditInviteRevoked,
+		Before:  map[string]any{"token": token, "revoked": invite.Revoked},
+		After:   map[string]any{"token": token, "revoked": true},
+	})
+	return nil
+}
+
+// RedeemInvite adds the user to the invite's room. Members redeeming again
+// get the room back without consuming a use. The returned bool reports
+// whether the user was newly added.
+func (s *InviteService) RedeemInvite(ctx context.Context, token, userID string) (*domain.Room, bool, error) {
+	invite, err := s.inviteRepo.FindByToken(ctx, token)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, false, ErrInviteInvalid
+		}
+		return nil, false, err
+	}
+
+	room, err := s.roomRepo.GetChatRoomsByRoomID(ctx, invite.RoomID)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, false, ErrRoomNotFound
+		}
+		return nil, false, err
+	}
+	if room.IsMember(userID) {
+		return room, false, nil
+	}
+	if room.IsBanned(userID) {
+		return nil, false, ErrUserBanned
+	}
+	if room.IsArchived() {
+		return nil, false, ErrRoomArchived
+	}
+
+	if _, err := s.inviteRepo.ConsumeUse(ctx, token, time.Now()); err != nil {
+		if isNotFound(err) {
+			return nil, false, ErrInviteInvalid
+		}
+		return nil, false, err
+	}
+	if err := s.roomRepo.JoinRoom(ctx, room.ID, userID); err != nil {
+		return nil, false, err
+	}
+
+	room.MemberIDs = append(room.MemberIDs, userID)
+	return room, true, nil
+}
+
+func newInviteToken() (string, error) {
+	b := make([]byte, 18)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -6,6 +6,8 @@ import (
 	"log"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/napat2224/socket-programming-chat-app/internal/domain"
 	"github.com/napat2224/socket-programming-chat-app/internal/repository"
@@ -65,6 +67,77 @@ func (s *UserService) GetMe(ctx context.Context, userID string) (*domain.User, e
 	return user, nil
 }
 
+const (
+	maxStatusTextLength    = 128
+	maxStatusExpirySeconds = 7 * 24 * 60 * 60
+)
+
+// UpdatePresence applies a partial presence update. Choosing the invisible
+// status turns AppearOffline on and any other status turns it off.
+func (s *UserService) UpdatePresence(ctx context.Context, userID string, update domain.PresenceUpdate) (*domain.User, error) {
+	set := map[string]interface{}{}
+
+	if update.Status != nil {
+		if !update.Status.IsValid() {
+			return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidInput, *update.Status)
+		}
+		hidden := *update.Status == domain.PresenceInvisible
+		if update.AppearOffline != nil && *update.AppearOffline != hidden {
+			return nil, fmt.Errorf("%w: status conflicts with appearOffline", ErrInvalidInput)
+		}
+		set["appear_offline"] = hidden
+		if !hidden {
+			set["status"] = string(*update.Status)
+		}
+	} else if update.AppearOffline != nil {
+		set["appear_offline"] = *update.AppearOffline
+	}
+
+	if update.StatusText != nil {
+		text := strings.TrimSpace(*update.StatusText)
+		if utf8.RuneCountInString(text) > maxStatusTextLength {
+			return nil, fmt.Errorf("%w: status text must be at most %d characters", ErrInvalidInput, maxStatusTextLength)
+		}
+		set["status_text"] = text
+	}
+
+	if update.ExpiresInSeconds != nil {
+		seconds := *update.ExpiresInSeconds
+		if seconds < 0 || seconds > maxStatusExpirySeconds {
+			return nil, fmt.Errorf("%w: expiry must be between 0 and %d seconds", ErrInvalidInput, maxStatusExpirySeconds)
+		}
+		var expiresAt *time.Time
+		if seconds > 0 {
+			t := time.Now().Add(time.Duration(seconds) * time.Second)
+			expiresAt = &t
+		}
+		set["status_expires_at"] = expiresAt
+	} else if _, ok := set["status"]; ok {
+		set["status_expires_at"] = nil
+	} else if _, ok := set["status_text"]; ok {
+		set["status_expires_at"] = nil
+	}
+
+	if update.HideLastSeen != nil {
+		set["hide_last_seen"] = *update.HideLastSeen
+	}
+
+	if len(set) == 0 {
+		return nil, fmt.Errorf("%w: no presence settings to update", ErrInvalidInput)
+	}
+	return s.repo.Update(ctx, userID, set)
+}
+
+// RecordLastSeen stores at as the last-seen time of the users.
+func (s *UserService) RecordLastSeen(ctx context.Context, userIDs []string, at time.Time) error {
+	return s.repo.TouchLastSeen(ctx, userIDs, at)
+}
+
+// GetUser looks up another user. It returns nil when the user does not exist.
+func (s *UserService) GetUser(ctx context.Context, userID string) (*domain.User, error) {
+	return s.repo.FindById(ctx, userID)
+}
+
 func (s *UserService) IsUsernameAvailable(ctx context.Context, name string) (bool, error) {
 	name = strings.TrimSpace(name)
 	if name == "" {
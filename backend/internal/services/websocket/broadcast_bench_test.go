@@ -0,0 +1,177 @@
+package websocket
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	fastws "github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+)
+
+// benchRoom is a room of real WebSocket connections on loopback: the server
+// side is registered in a Hub and the client side drains every frame.
+type benchRoom struct {
+	hub     *Hub
+	conns   []*Connection
+	clients []*fastws.Conn
+	app     *fiber.App
+	done    sync.WaitGroup
+}
+
+func newBenchRoom(b *testing.B, members int, subprotocol string, compression Compression) *benchRoom {
+	b.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		b.Fatal(err)
+	}
+	registered := make(chan *Connection)
+	r := &benchRoom{hub: NewHub(), app: fiber.New(fiber.Config{DisableStartupMessage: true})}
+	r.app.Get("/ws", websocket.New(func(c *websocket.Conn) {
+		conn := NewConnection(c)
+		if err := conn.SetCompression(compression); err != nil {
+			b.Error(err)
+		}
+		registered <- conn
+		for {
+			if _, err := conn.Read(); err != nil {
+				return
+			}
+		}
+	}, websocket.Config{Subprotocols: Subprotocols, EnableCompression: compression.Enabled}))
+	go func() { _ = r.app.Listener(ln) }()
+
+	dialer := *fastws.DefaultDialer
+	dialer.Subprotocols = []string{subprotocol}
+	dialer.EnableCompression = compression.Enabled
+	url := "ws://" + ln.Addr().String() + "/ws"
+
+	// Dial in parallel; sequential handshakes dominate setup otherwise.
+	var mu sync.Mutex
+	var dials sync.WaitGroup
+	dialErr := make(chan error, members)
+	next := make(chan int)
+	for w := 0; w < 32; w++ {
+		dials.Add(1)
+		go func() {
+			defer dials.Done()
+			for i := range next {
+				client, _, err := dialer.Dial(url, nil)
+				if err != nil {
+					dialErr <- fmt.Errorf("dial member %d: %w", i, err)
+					continue
+				}
+				mu.Lock()
+				r.clients = append(r.clients, client)
+				mu.Unlock()
+				r.done.Add(1)
+				go r.drain(client)
+			}
+		}()
+	}
+	go func() {
+		for i := 0; i < members; i++ {
+			next <- i
+		}
+		close(next)
+	}()
+
+	for i := 0; i < members; i++ {
+		var conn *Connection
+		select {
+		case conn = <-registered:
+		case err := <-dialErr:
+			dials.Wait()
+			r.close()
+			b.Fatal(err)
+		}
+		// Appearing offline keeps each join from announcing itself to the
+		// whole room, which would make setup quadratic.
+		userId := fmt.Sprintf("u%d", i)
+		r.hub.SetAppearOffline(userId, true)
+		r.hub.AddUser(UserPresenceData{UserId: userId}, conn)
+		r.hub.AddToRoom("bench", conn)
+		r.conns = append(r.conns, conn)
+	}
+	dials.Wait()
+	return r
+}
+
+// drain reads and discards frames until the client closes.
+func (r *benchRoom) drain(client *fastws.Conn) {
+	defer r.done.Done()
+	for {
+		_, rd, err := client.NextReader()
+		if err != nil {
+			return
+		}
+		_, _ = io.Copy(io.Discard, rd)
+	}
+}
+
+func (r *benchRoom) close() {
+	for _, client := range r.clients {
+		_ = client.Close()
+	}
+	r.done.Wait()
+	_ = r.app.Shutdown()
+}
+
+// BenchmarkRoomBroadcast compares writing one message to every member of a
+// large room the old way, encoding, framing and compressing it per
+// recipient, with the Hub's shared prepared frame.
+func BenchmarkRoomBroadcast(b *testing.B) {
+	if testing.Short() {
+		b.Skip("opens thousands of loopback connections")
+	}
+	payload := MustMarshal(WsMessage{
+		Type: TypeTextMessage,
+		Data: MustMarshal(OutgoingTextData{
+			MessageId:  "6650f1c2a4b5c6d7e8f90123",
+			SenderId:   "sender",
+			SenderName: "Sender",
+			RoomId:     "bench",
+			Content:    strings.Repeat("hello room ", 24),
+		}),
+	})
+
+	deflate := Compression{Enabled: true, Level: DefaultCompressionLevel}
+	cases := []struct {
+		name        string
+		subprotocol string
+		compression Compression
+	}{
+		{"json", SubprotocolJSON, Compression{}},
+		{"msgpack", SubprotocolMsgpack, Compression{}},
+		{"json+deflate", SubprotocolJSON, deflate},
+	}
+
+	for _, members := range []int{1000, 4000} {
+		for _, tc := range cases {
+			room := newBenchRoom(b, members, tc.subprotocol, tc.compression)
+			name := fmt.Sprintf("members=%d/%s", members, tc.name)
+
+			b.Run(name+"/per-recipient", func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					for _, conn := range room.conns {
+						if err := conn.Send(payload); err != nil {
+							b.Fatal(err)
+						}
+					}
+				}
+			})
+			b.Run(name+"/prepared", func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					room.hub.BroadcastToRoom("bench", payload)
+				}
+			})
+			room.close()
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package websocket
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Subprotocol names clients may request on /ws. When a client asks for
+// several, the first match in Subprotocols wins; without one the connection
+// speaks JSON.
+const (
+	SubprotocolJSON    = "chat.v1.json"
+	SubprotocolMsgpack = "chat.v1.msgpack"
+)
+
+var Subprotocols = []string{SubprotocolMsgpack, SubprotocolJSON}
+
+// Codec frames envelopes on the wire. The Hub and handlers always work with
+// JSON; a codec converts that canonical form to and from its own encoding.
+type Codec interface {
+	Name() string
+	// Binary reports whether frames go out as binary WebSocket messages.
+	Binary() bool
+	Encode(jsonPayload []byte) ([]byte, error)
+	Decode(frame []byte) ([]byte, error)
+}
+
+var (
+	JSONCodec    Codec = jsonCodec{}
+	MsgpackCodec Codec = msgpackCodec{}
+)
+
+// CodecForSubprotocol returns the codec for a negotiated subprotocol.
+func CodecForSubprotocol(subprotocol string) Codec {
+	if subprotocol == SubprotocolMsgpack {
+		return MsgpackCodec
+	}
+	return JSONCodec
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string                          { return "json" }
+func (jsonCodec) Binary() bool                          { return false }
+func (jsonCodec) Encode(payload []byte) ([]byte, error) { return payload, nil }
+func (jsonCodec) Decode(frame []byte) ([]byte, error)   { return frame, nil }
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Name() string { return "msgpack" }
+func (msgpackCodec) Binary() bool { return true }
+
+func (msgpackCodec) Encode(payload []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return appendMsgpack(nil, v)
+}
+
+func (msgpackCodec) Decode(frame []byte) ([]byte, error) {
+	v, rest, err := readMsgpack(frame, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errMsgpackTrailing
+	}
+	return json.Marshal(v)
+}
@@ -0,0 +1,139 @@
+package websocket
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestMsgpackRoundTrip(t *testing.T) {
+	cases := []string{
+		`null`,
+		`true`,
+		`[false,0,-1,-33,127,128,-129,40000,-40000,4294967296,-4294967296,1.5]`,
+		`{"type":"text_message","data":{"content":"` + strings.Repeat("x", 300) + `","n":[]},"status":""}`,
+		`{"big":` + strings.Repeat(`[`, 3) + `"` + strings.Repeat("y", 70000) + `"` + strings.Repeat(`]`, 3) + `}`,
+	}
+	for _, in := range cases {
+		frame, err := MsgpackCodec.Encode([]byte(in))
+		if err != nil {
+			t.Fatalf("Encode(%.40s): %v", in, err)
+		}
+		out, err := MsgpackCodec.Decode(frame)
+		if err != nil {
+			t.Fatalf("Decode(%.40s): %v", in, err)
+		}
+		if !jsonEqual(t, []byte(in), out) {
+			t.Fatalf("round trip of %.40s gave %.40s", in, out)
+		}
+	}
+}
+
+func TestMsgpackRejectsTruncatedFrames(t *testing.T) {
+	frame, err := MsgpackCodec.Encode([]byte(`{"type":"message","data":{"content":"hello"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n := 0; n < len(frame); n++ {
+		if _, err := MsgpackCodec.Decode(frame[:n]); err == nil {
+			t.Fatalf("Decode of %d/%d bytes succeeded", n, len(frame))
+		}
+	}
+	if _, err := MsgpackCodec.Decode(append(frame, 0xc0)); err == nil {
+		t.Fatal("Decode accepted trailing data")
+	}
+}
+
+func TestCodecForSubprotocol(t *testing.T) {
+	if CodecForSubprotocol(SubprotocolMsgpack) != MsgpackCodec {
+		t.Fatal("msgpack subprotocol did not select MsgpackCodec")
+	}
+	for _, p := range []string{"", SubprotocolJSON, "unknown"} {
+		if CodecForSubprotocol(p) != JSONCodec {
+			t.Fatalf("subprotocol %q did not fall back to JSON", p)
+		}
+	}
+}
+
+// countingCodec counts how often a broadcast is encoded.
+type countingCodec struct {
+	Codec
+	encodes *atomic.Int64
+}
+
+func (c countingCodec) Encode(payload []byte) ([]byte, error) {
+	c.encodes.Add(1)
+	return c.Codec.Encode(payload)
+}
+
+func TestBroadcastEncodesOncePerCodec(t *testing.T) {
+	h := NewHub()
+	var jsonEncodes, msgpackEncodes atomic.Int64
+	jsonCodec := countingCodec{JSONCodec, &jsonEncodes}
+	msgpackCodec := countingCodec{MsgpackCodec, &msgpackEncodes}
+
+	var conns []*FakeConn
+	for i := 0; i < 10; i++ {
+		codec := Codec(jsonCodec)
+		if i%2 == 1 {
+			codec = msgpackCodec
+		}
+		conn := NewFakeConnWithCodec("test", codec)
+		h.AddUser(UserPresenceData{UserId: string(rune('a' + i))}, conn)
+		h.AddToRoom("r", conn)
+		conns = append(conns, conn)
+	}
+	jsonEncodes.Store(0)
+	msgpackEncodes.Store(0)
+
+	h.BroadcastToRoom("r", MustMarshal(WsMessage{Type: TypeTextMessage, Data: MustMarshal(OutgoingTextData{Content: "hi", RoomId: "r"})}))
+
+	if jsonEncodes.Load() != 1 || msgpackEncodes.Load() != 1 {
+		t.Fatalf("encodes json=%d msgpack=%d, want 1 each", jsonEncodes.Load(), msgpackEncodes.Load())
+	}
+	for i, conn := range conns {
+		got := conn.OfType(TypeTextMessage)
+		if len(got) != 1 {
+			t.Fatalf("conn %d got %d text messages, want 1", i, len(got))
+		}
+		sent := conn.Sent()
+		isJSON := bytes.HasPrefix(sent[len(sent)-1], []byte("{"))
+		if isJSON != (i%2 == 0) {
+			t.Fatalf("conn %d received the wrong encoding", i)
+		}
+	}
+}
+
+func jsonEqual(t *testing.T, a, b []byte) bool {
+	t.Helper()
+	var x, y any
+	if err := json.Unmarshal(a, &x); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, &y); err != nil {
+		t.Fatal(err)
+	}
+	xs, _ := json.Marshal(x)
+	ys, _ := json.Marshal(y)
+	return bytes.Equal(xs, ys)
+}
+
+func TestMsgpackRejectsDeepNesting(t *testing.T) {
+	// A frame of one-element arrays used to recurse until the stack
+	// overflowed.
+	frame := bytes.Repeat([]byte{0x91}, 1<<20)
+	if _, err := MsgpackCodec.Decode(frame); err == nil {
+		t.Fatal("Decode accepted deeply nested arrays")
+	}
+	frame = bytes.Repeat([]byte{0x81, 0xa1, 'k'}, 1<<16)
+	if _, err := MsgpackCodec.Decode(frame); err == nil {
+		t.Fatal("Decode accepted deeply nested maps")
+	}
+
+	nested := append(bytes.Repeat([]byte{0x91}, maxMsgpackDepth), 0xc0)
+	if _, err := MsgpackCodec.Decode(nested); err != nil {
+		t.Fatalf("Decode rejected nesting at the limit: %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package websocket
+
+import (
+	"compress/flate"
+	"fmt"
+)
+
+// Compression configures permessage-deflate (RFC 7692) on WebSocket
+// connections. Only clients that negotiate the extension during the
+// handshake get compressed frames; everyone else is unaffected.
+type Compression struct {
+	Enabled bool
+	// Level is a compress/flate level, from HuffmanOnly (-2) to
+	// BestCompression (9).
+	Level int
+	// Threshold is the smallest frame, in bytes, worth compressing. Smaller
+	// frames go out uncompressed.
+	Threshold int
+}
+
+// DefaultCompressionLevel favours speed: chat envelopes are small and
+// repetitive, so most of the gain comes from the cheapest level.
+const DefaultCompressionLevel = flate.BestSpeed
+
+func (c Compression) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Level < flate.HuffmanOnly || c.Level > flate.BestCompression {
+		return fmt.Errorf("compression level %d out of range [%d, %d]", c.Level, flate.HuffmanOnly, flate.BestCompression)
+	}
+	if c.Threshold < 0 {
+		return fmt.Errorf("compression threshold %d is negative", c.Threshold)
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package websocket
+
+// Conn is what the Hub needs from a client connection, whatever transport
+// carries it. *Connection implements it for WebSocket, TCP and SSE clients.
+type Conn interface {
+	// Send writes one JSON envelope, encoding it with the connection's
+	// codec. It must be safe for concurrent use.
+	Send(payload []byte) error
+	// SendFrame writes a frame already encoded with Codec, so broadcasts
+	// can encode once per codec rather than once per recipient.
+	SendFrame(f *Frame) error
+	// Codec is the wire encoding negotiated for the connection.
+	Codec() Codec
+	// CloseWithCode tells the client why it is being disconnected and closes
+	// the connection.
+	CloseWithCode(code int, reason string)
+	// Session identifies the connection and the device behind it.
+	Session() SessionInfo
+}
@@ -0,0 +1,169 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrFakeClosed is returned by FakeConn.Send after the connection closed.
+var ErrFakeClosed = errors.New("fake connection closed")
+
+// FakeConn is an in-memory Conn for tests. It records every payload it is
+// sent and how it was closed.
+type FakeConn struct {
+	session SessionInfo
+	codec   Codec
+
+	mu        sync.Mutex
+	sent      [][]byte
+	closed    bool
+	closeCode int
+	sendErr   error
+	notify    chan struct{}
+}
+
+func NewFakeConn(device string) *FakeConn {
+	return &FakeConn{
+		session: SessionInfo{
+			Id:          newSessionId(),
+			Device:      device,
+			Transport:   "fake",
+			ConnectedAt: time.Now(),
+		},
+		codec:  JSONCodec,
+		notify: make(chan struct{}, 1),
+	}
+}
+
+// NewFakeConnWithCodec returns a FakeConn that speaks codec, as if it had been
+// negotiated by subprotocol.
+func NewFakeConnWithCodec(device string, codec Codec) *FakeConn {
+	f := NewFakeConn(device)
+	f.codec = codec
+	return f
+}
+
+func (f *FakeConn) Session() SessionInfo {
+	return f.session
+}
+
+func (f *FakeConn) Codec() Codec {
+	return f.codec
+}
+
+func (f *FakeConn) Send(payload []byte) error {
+	frame, err := NewFrame(f.codec, payload)
+	if err != nil {
+		return err
+	}
+	return f.SendFrame(frame)
+}
+
+// SendFrame records the frame's payload as sent; Sent returns payloads in
+// the connection's codec.
+func (f *FakeConn) SendFrame(frame *Frame) error {
+	payload := frame.Data()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return ErrFakeClosed
+	}
+	if f.sendErr != nil {
+		return f.sendErr
+	}
+	msg := make([]byte, len(payload))
+	copy(msg, payload)
+	f.sent = append(f.sent, msg)
+
+	select {
+	case f.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (f *FakeConn) CloseWithCode(code int, reason string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	f.closeCode = code
+}
+
+// FailSends makes every later Send return err; nil restores normal sends.
+func (f *FakeConn) FailSends(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sendErr = err
+}
+
+// Closed reports whether the connection was closed and with which code.
+func (f *FakeConn) Closed() (bool, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.closed, f.closeCode
+}
+
+// Sent returns a copy of every payload sent so far.
+func (f *FakeConn) Sent() [][]byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]byte(nil), f.sent...)
+}
+
+// Envelopes decodes every payload sent so far, skipping undecodable ones.
+func (f *FakeConn) Envelopes() []WsMessage {
+	sent := f.Sent()
+	envelopes := make([]WsMessage, 0, len(sent))
+	for _, frame := range sent {
+		raw, err := f.codec.Decode(frame)
+		if err != nil {
+			continue
+		}
+		var envelope WsMessage
+		if json.Unmarshal(raw, &envelope) == nil {
+			envelopes = append(envelopes, envelope)
+		}
+	}
+	return envelopes
+}
+
+// OfType returns the sent envelopes of the given type.
+func (f *FakeConn) OfType(t MessageType) []WsMessage {
+	var matched []WsMessage
+	for _, envelope := range f.Envelopes() {
+		if envelope.Type == t {
+			matched = append(matched, envelope)
+		}
+	}
+	return matched
+}
+
+// Reset forgets everything sent so far.
+func (f *FakeConn) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = nil
+}
+
+// WaitForSent blocks until at least n payloads were sent or the timeout
+// passes, and reports whether n was reached.
+func (f *FakeConn) WaitForSent(n int, timeout time.Duration) bool {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		f.mu.Lock()
+		count := len(f.sent)
+		f.mu.Unlock()
+		if count >= n {
+			return true
+		}
+		select {
+		case <-f.notify:
+		case <-deadline.C:
+			return false
+		}
+	}
+}
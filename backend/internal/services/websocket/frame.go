@@ -0,0 +1,51 @@
+package websocket
+
+import (
+	"sync"
+
+	fastws "github.com/fasthttp/websocket"
+)
+
+// Frame is a payload encoded once for one codec and shared by every
+// recipient that speaks it. WebSocket recipients write it as a prepared
+// message, so the wire frame, compressed or not, is also built once per
+// broadcast rather than once per connection.
+type Frame struct {
+	codec Codec
+	data  []byte
+
+	once     sync.Once
+	prepared *fastws.PreparedMessage
+	err      error
+}
+
+// NewFrame encodes a JSON envelope with codec.
+func NewFrame(codec Codec, payload []byte) (*Frame, error) {
+	data, err := codec.Encode(payload)
+	if err != nil {
+		return nil, err
+	}
+	return &Frame{codec: codec, data: data}, nil
+}
+
+func (f *Frame) Codec() Codec {
+	return f.codec
+}
+
+// Data is the encoded payload. Callers must not modify it.
+func (f *Frame) Data() []byte {
+	return f.data
+}
+
+// preparedMessage builds the WebSocket message on first use, so broadcasts
+// that reach only TCP or SSE clients never pay for it.
+func (f *Frame) preparedMessage() (*fastws.PreparedMessage, error) {
+	f.once.Do(func() {
+		kind := fastws.TextMessage
+		if f.codec.Binary() {
+			kind = fastws.BinaryMessage
+		}
+		f.prepared, f.err = fastws.NewPreparedMessage(kind, f.data)
+	})
+	return f.prepared, f.err
+}
@@ -2,57 +2,79 @@ package websocket
 
 import (
 	"log"
+	"sort"
 	"sync"
+	"time"
 )
 
 type Hub struct {
 	mu       sync.RWMutex
-	users    map[string]map[*Connection]struct{}
-	rooms    map[string]map[*Connection]struct{}
-	connUser map[*Connection]string
+	users    map[string]map[Conn]struct{}
+	rooms    map[string]map[Conn]struct{}
+	connUser map[Conn]string
 	userInfo map[string]UserPresenceData
+	// hidden holds the users that chose to appear offline.
+	hidden map[string]struct{}
+	// lastActive and idle drive automatic away detection.
+	lastActive map[Conn]time.Time
+	idle       map[string]struct{}
 }
 
 func NewHub() *Hub {
 	return &Hub{
-		users:    make(map[string]map[*Connection]struct{}),
-		rooms:    make(map[string]map[*Connection]struct{}),
-		connUser: make(map[*Connection]string),
+		users:    make(map[string]map[Conn]struct{}),
+		rooms:    make(map[string]map[Conn]struct{}),
+		connUser: make(map[Conn]string),
 		userInfo: make(map[string]UserPresenceData),
+		hidden:   make(map[string]struct{}),
+
+		lastActive: make(map[Conn]time.Time),
+		idle:       make(map[string]struct{}),
 	}
 }
 
-func (h *Hub) AddUser(info UserPresenceData, conn *Connection) {
+// AddUser registers the connection and reports whether it is the user's
+// first live one.
+func (h *Hub) AddUser(info UserPresenceData, conn Conn) (first bool) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
 	conns := h.users[info.UserId]
+	first = len(conns) == 0
 	if conns == nil {
-		conns = make(map[*Connection]struct{})
+		conns = make(map[Conn]struct{})
 		h.users[info.UserId] = conns
 	}
 	conns[conn] = struct{}{}
 	h.connUser[conn] = info.UserId
 	h.userInfo[info.UserId] = info
+	h.lastActive[conn] = time.Now()
+	delete(h.idle, info.UserId)
 
 	log.Printf("[hub] user %s connected (conns: %d)", info.UserId, len(conns))
+	return first
 }
 
-func (h *Hub) Remove(conn *Connection) (userId string, last bool) {
+func (h *Hub) Remove(conn Conn) (userId string, last bool) {
 	h.mu.Lock()
-	defer h.mu.Unlock()
 
 	userId, ok := h.connUser[conn]
 	if !ok {
+		h.mu.Unlock()
 		return "", false
 	}
 	delete(h.connUser, conn)
+	delete(h.lastActive, conn)
+	info := h.presenceLocked(userId)
+	_, hidden := h.hidden[userId]
 
 	if conns, ok := h.users[userId]; ok {
 		delete(conns, conn)
 		if len(conns) == 0 {
 			delete(h.users, userId)
 			delete(h.userInfo, userId)
+			delete(h.hidden, userId)
+			delete(h.idle, userId)
 			last = true
 			log.Printf("[hub] user %s has no more connections", userId)
 		} else {
@@ -60,6 +82,7 @@ func (h *Hub) Remove(conn *Connection) (userId string, last bool) {
 		}
 	}
 
+	var leftRooms []string
 	for roomId, conns := range h.rooms {
 		if _, ok := conns[conn]; ok {
 			delete(conns, conn)
@@ -67,8 +90,16 @@ func (h *Hub) Remove(conn *Connection) (userId string, last bool) {
 				delete(h.rooms, roomId)
 				log.Printf("[hub] room %s is now empty", roomId)
 			}
+			if !hidden && !h.userInRoomLocked(roomId, userId) {
+				leftRooms = append(leftRooms, roomId)
+			}
 		}
 	}
+	h.mu.Unlock()
+
+	for _, roomId := range leftRooms {
+		h.broadcastRoomPresence(roomId, info, StatusOffline)
+	}
 
 	return userId, last
 }
@@ -84,7 +115,7 @@ func (h *Hub) OnlineUsers() []UserPresenceData {
 	return result
 }
 
-func (h *Hub) UserIDForConn(conn *Connection) string {
+func (h *Hub) UserIDForConn(conn Conn) string {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 	return h.connUser[conn]
@@ -97,62 +128,372 @@ func (h *Hub) UserInfo(userId string) (UserPresenceData, bool) {
 	return u, ok
 }
 
-func (h *Hub) AddToRoom(roomId string, conn *Connection) {
+func (h *Hub) AddToRoom(roomId string, conn Conn) {
 	h.mu.Lock()
-	defer h.mu.Unlock()
+
+	userId := h.connUser[conn]
+	_, hidden := h.hidden[userId]
+	entered := userId != "" && !hidden && !h.userInRoomLocked(roomId, userId)
+	info := h.presenceLocked(userId)
 
 	conns := h.rooms[roomId]
 	if conns == nil {
-		conns = make(map[*Connection]struct{})
+		conns = make(map[Conn]struct{})
 		h.rooms[roomId] = conns
 	}
 	conns[conn] = struct{}{}
 
 	log.Printf("[hub] conn %p joined room %s (conns: %d)", conn, roomId, len(conns))
+	h.mu.Unlock()
+
+	if entered {
+		h.broadcastRoomPresence(roomId, info, info.Status)
+	}
+}
+
+// RemoveFromRoom unsubscribes a single connection from the room.
+func (h *Hub) RemoveFromRoom(roomId string, conn Conn) {
+	h.mu.Lock()
+
+	conns, ok := h.rooms[roomId]
+	if _, subscribed := conns[conn]; !ok || !subscribed {
+		h.mu.Unlock()
+		return
+	}
+	delete(conns, conn)
+	if len(conns) == 0 {
+		delete(h.rooms, roomId)
+		log.Printf("[hub] room %s is now empty", roomId)
+	}
+
+	userId := h.connUser[conn]
+	_, hidden := h.hidden[userId]
+	left := userId != "" && !hidden && !h.userInRoomLocked(roomId, userId)
+	info := h.presenceLocked(userId)
+	h.mu.Unlock()
+
+	if left {
+		h.broadcastRoomPresence(roomId, info, StatusOffline)
+	}
+}
+
+// userInRoomLocked reports whether any connection of the user is subscribed
+// to the room. Callers must hold h.mu.
+func (h *Hub) userInRoomLocked(roomId string, userId string) bool {
+	conns := h.rooms[roomId]
+	if len(conns) == 0 {
+		return false
+	}
+	for c := range h.users[userId] {
+		if _, ok := conns[c]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// broadcastRoomPresence tells a room that a user's first connection entered
+// it or their last one left. Callers must not hold h.mu.
+func (h *Hub) broadcastRoomPresence(roomId string, info UserPresenceData, status UserStatus) {
+	data := RoomPresenceData{
+		RoomId:  roomId,
+		UserId:  info.UserId,
+		Name:    info.Name,
+		Profile: info.Profile,
+	}
+	if status != StatusOffline {
+		data.StatusText = info.StatusText
+	}
+	h.BroadcastToRoom(roomId, MustMarshal(WsMessage{
+		Type:   TypeRoomPresence,
+		Status: status,
+		Data:   MustMarshal(data),
+	}))
+}
+
+func (h *Hub) IsOnline(userId string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.users[userId]) > 0
+}
+
+// AppearsOnline reports whether other users should see the user as online.
+func (h *Hub) AppearsOnline(userId string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	_, hidden := h.hidden[userId]
+	return !hidden && len(h.users[userId]) > 0
+}
+
+func (h *Hub) IsAppearingOffline(userId string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	_, hidden := h.hidden[userId]
+	return hidden
+}
+
+// SetAppearOffline records the user's "appear offline" choice and reports
+// whether it changed. Rooms the user is subscribed to are told about the
+// change; contacts are left to the caller.
+func (h *Hub) SetAppearOffline(userId string, hidden bool) (changed bool) {
+	h.mu.Lock()
+
+	_, was := h.hidden[userId]
+	if was == hidden {
+		h.mu.Unlock()
+		return false
+	}
+	if hidden {
+		h.hidden[userId] = struct{}{}
+	} else {
+		delete(h.hidden, userId)
+	}
+
+	info := h.presenceLocked(userId)
+	rooms := h.userRoomsLocked(userId)
+	h.mu.Unlock()
+
+	status := info.Status
+	if hidden {
+		status = StatusOffline
+	}
+	for _, roomId := range rooms {
+		h.broadcastRoomPresence(roomId, info, status)
+	}
+	return true
+}
+
+// VisibleOnlineUsers returns the given users that are online and not
+// appearing offline.
+func (h *Hub) VisibleOnlineUsers(userIds []string) []UserPresenceData {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	result := make([]UserPresenceData, 0, len(userIds))
+	for _, userId := range userIds {
+		if _, hidden := h.hidden[userId]; hidden || len(h.users[userId]) == 0 {
+			continue
+		}
+		result = append(result, h.presenceLocked(userId))
+	}
+	return result
 }
 
 func (h *Hub) BroadcastToRoom(roomId string, payload []byte) {
 	h.mu.RLock()
-	conns, ok := h.rooms[roomId]
+	targets := connList(h.rooms[roomId])
 	h.mu.RUnlock()
 
-	if !ok || len(conns) == 0 {
+	if len(targets) == 0 {
 		log.Printf("[hub] no active connections in room %s", roomId)
 		return
 	}
+	sendAll(targets, payload, "room "+roomId)
+}
 
-	for c := range conns {
-		if err := c.Send(payload); err != nil {
-			log.Printf("[hub] failed to send to room %s: %v", roomId, err)
+func (h *Hub) BroadcastToAll(payload []byte) {
+	h.BroadcastToAllExcept(nil, payload)
+}
+
+func (h *Hub) BroadcastToAllExcept(except Conn, payload []byte) {
+	h.mu.RLock()
+	targets := make([]Conn, 0, len(h.connUser))
+	for c := range h.connUser {
+		if c != except {
+			targets = append(targets, c)
 		}
 	}
+	h.mu.RUnlock()
+
+	sendAll(targets, payload, "everyone")
 }
 
-func (h *Hub) BroadcastToAll(payload []byte) {
+// RemoveUserFromRoom unsubscribes every connection of the user from the room.
+func (h *Hub) RemoveUserFromRoom(roomId string, userId string) {
+	h.mu.Lock()
+
+	conns, ok := h.rooms[roomId]
+	if !ok {
+		h.mu.Unlock()
+		return
+	}
+	_, hidden := h.hidden[userId]
+	left := !hidden && h.userInRoomLocked(roomId, userId)
+	info := h.presenceLocked(userId)
+	for c := range h.users[userId] {
+		delete(conns, c)
+	}
+	if len(conns) == 0 {
+		delete(h.rooms, roomId)
+		log.Printf("[hub] room %s is now empty", roomId)
+	}
+	h.mu.Unlock()
+
+	if left {
+		h.broadcastRoomPresence(roomId, info, StatusOffline)
+	}
+}
+
+func (h *Hub) SendToUser(userId string, payload []byte) {
+	h.mu.RLock()
+	targets := connList(h.users[userId])
+	h.mu.RUnlock()
+
+	sendAll(targets, payload, "user "+userId)
+}
+
+// BroadcastToRoomAndUsers sends the payload once to every connection that is
+// either subscribed to the room or belongs to one of the users.
+func (h *Hub) BroadcastToRoomAndUsers(roomId string, userIds []string, payload []byte) {
+	h.mu.RLock()
+	targets := make(map[Conn]struct{}, len(h.rooms[roomId]))
+	for c := range h.rooms[roomId] {
+		targets[c] = struct{}{}
+	}
+	for _, userId := range userIds {
+		for c := range h.users[userId] {
+			targets[c] = struct{}{}
+		}
+	}
+	h.mu.RUnlock()
+
+	sendAll(connList(targets), payload, "room "+roomId)
+}
+
+// AddUserToRoom subscribes every live connection of the user to the room.
+func (h *Hub) AddUserToRoom(roomId string, userId string) {
+	h.mu.Lock()
+
+	userConns := h.users[userId]
+	if len(userConns) == 0 {
+		h.mu.Unlock()
+		return
+	}
+	_, hidden := h.hidden[userId]
+	entered := !hidden && !h.userInRoomLocked(roomId, userId)
+	info := h.presenceLocked(userId)
+
+	conns := h.rooms[roomId]
+	if conns == nil {
+		conns = make(map[Conn]struct{})
+		h.rooms[roomId] = conns
+	}
+	for c := range userConns {
+		conns[c] = struct{}{}
+	}
+
+	log.Printf("[hub] user %s joined room %s (conns: %d)", userId, roomId, len(conns))
+	h.mu.Unlock()
+
+	if entered {
+		h.broadcastRoomPresence(roomId, info, info.Status)
+	}
+}
+
+func (h *Hub) SendToUsers(userIds []string, payload []byte) {
+	h.mu.RLock()
+	var targets []Conn
+	for _, userId := range userIds {
+		for c := range h.users[userId] {
+			targets = append(targets, c)
+		}
+	}
+	h.mu.RUnlock()
+
+	sendAll(targets, payload, "users")
+}
+
+// RemoveRoom drops every subscription to the room.
+func (h *Hub) RemoveRoom(roomId string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.rooms, roomId)
+	log.Printf("[hub] room %s removed", roomId)
+}
+
+// OnlineCount returns how many of the given users appear online: they have
+// a live connection and are not appearing offline.
+func (h *Hub) OnlineCount(userIds []string) int {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
-	for _, conns := range h.users {
-		for c := range conns {
-			if err := c.Send(payload); err != nil {
-				log.Printf("[hub] failed to broadcast: %v", err)
-			}
+	count := 0
+	for _, userId := range userIds {
+		if _, hidden := h.hidden[userId]; !hidden && len(h.users[userId]) > 0 {
+			count++
 		}
 	}
+	return count
 }
 
-func (h *Hub) BroadcastToAllExcept(except *Connection, payload []byte) {
+// Sessions lists the user's live connections, oldest first.
+func (h *Hub) Sessions(userId string) []SessionInfo {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
-	for _, conns := range h.users {
-		for c := range conns {
-			if c == except {
-				continue
-			}
-			if err := c.Send(payload); err != nil {
-				log.Printf("[hub] failed to broadcast: %v", err)
+	sessions := make([]SessionInfo, 0, len(h.users[userId]))
+	for c := range h.users[userId] {
+		sessions = append(sessions, c.Session())
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].ConnectedAt.Before(sessions[j].ConnectedAt)
+	})
+	return sessions
+}
+
+// FindSession returns the user's connection with the session id, or nil.
+func (h *Hub) FindSession(userId string, sessionId string) Conn {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for c := range h.users[userId] {
+		if c.Session().Id == sessionId {
+			return c
+		}
+	}
+	return nil
+}
+
+// CloseSession closes one of the user's connections with
+// CloseSessionTerminated. It reports whether the session was found.
+func (h *Hub) CloseSession(userId string, sessionId string) bool {
+	target := h.FindSession(userId, sessionId)
+	if target == nil {
+		return false
+	}
+	log.Printf("[hub] terminating session %s of user %s", sessionId, userId)
+	target.CloseWithCode(CloseSessionTerminated, "session terminated")
+	return true
+}
+
+// connList copies a connection set so it can be used after h.mu is released.
+func connList(set map[Conn]struct{}) []Conn {
+	conns := make([]Conn, 0, len(set))
+	for c := range set {
+		conns = append(conns, c)
+	}
+	return conns
+}
+
+// sendAll writes the payload to every connection, encoding it once per codec
+// in use among them. It must be called without h.mu held so a slow client
+// cannot stall the Hub.
+func sendAll(conns []Conn, payload []byte, target string) {
+	frames := make(map[string]*Frame, 2)
+	for _, c := range conns {
+		codec := c.Codec()
+		frame, ok := frames[codec.Name()]
+		if !ok {
+			var err error
+			if frame, err = NewFrame(codec, payload); err != nil {
+				log.Printf("[hub] failed to encode %s frame for %s: %v", codec.Name(), target, err)
+				return
 			}
+			frames[codec.Name()] = frame
+		}
+		if err := c.SendFrame(frame); err != nil {
+			log.Printf("[hub] failed to send to %s: %v", target, err)
 		}
 	}
 }
@@ -0,0 +1,361 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+func connect(h *Hub, userId string) *FakeConn {
+	conn := NewFakeConn("test")
+	h.AddUser(UserPresenceData{UserId: userId, Name: userId}, conn)
+	return conn
+}
+
+type presenceEvent struct {
+	Status UserStatus
+	Data   RoomPresenceData
+}
+
+func roomPresence(t *testing.T, conn *FakeConn) []presenceEvent {
+	t.Helper()
+	var events []presenceEvent
+	for _, envelope := range conn.OfType(TypeRoomPresence) {
+		var data RoomPresenceData
+		if err := json.Unmarshal(envelope.Data, &data); err != nil {
+			t.Fatalf("decode room presence: %v", err)
+		}
+		events = append(events, presenceEvent{envelope.Status, data})
+	}
+	return events
+}
+
+func TestAddUserReportsFirstAndRemoveReportsLast(t *testing.T) {
+	h := NewHub()
+	a1 := NewFakeConn("phone")
+	a2 := NewFakeConn("laptop")
+
+	if first := h.AddUser(UserPresenceData{UserId: "a"}, a1); !first {
+		t.Fatal("first connection not reported as first")
+	}
+	if first := h.AddUser(UserPresenceData{UserId: "a"}, a2); first {
+		t.Fatal("second connection reported as first")
+	}
+	if got := len(h.Sessions("a")); got != 2 {
+		t.Fatalf("sessions = %d, want 2", got)
+	}
+
+	if userId, last := h.Remove(a1); userId != "a" || last {
+		t.Fatalf("Remove(a1) = %q, %v; want a, false", userId, last)
+	}
+	if userId, last := h.Remove(a2); userId != "a" || !last {
+		t.Fatalf("Remove(a2) = %q, %v; want a, true", userId, last)
+	}
+	if h.IsOnline("a") {
+		t.Fatal("user still online after last connection left")
+	}
+	if userId, _ := h.Remove(a2); userId != "" {
+		t.Fatalf("removing twice returned %q", userId)
+	}
+}
+
+func TestBroadcastToRoomReachesOnlySubscribers(t *testing.T) {
+	h := NewHub()
+	a := connect(h, "a")
+	b := connect(h, "b")
+	c := connect(h, "c")
+	h.AddToRoom("r1", a)
+	h.AddToRoom("r1", b)
+	h.AddToRoom("r2", c)
+	for _, conn := range []*FakeConn{a, b, c} {
+		conn.Reset()
+	}
+
+	h.BroadcastToRoom("r1", []byte(`{"type":"message"}`))
+
+	if len(a.Sent()) != 1 || len(b.Sent()) != 1 {
+		t.Fatalf("subscribers got %d and %d payloads, want 1 each", len(a.Sent()), len(b.Sent()))
+	}
+	if len(c.Sent()) != 0 {
+		t.Fatalf("non-subscriber got %d payloads", len(c.Sent()))
+	}
+}
+
+func TestRoomPresenceOnFirstEnterAndLastLeave(t *testing.T) {
+	h := NewHub()
+	watcher := connect(h, "w")
+	h.AddToRoom("r", watcher)
+
+	a1 := connect(h, "a")
+	a2 := connect(h, "a")
+	watcher.Reset()
+
+	h.AddToRoom("r", a1)
+	h.AddToRoom("r", a2)
+	events := roomPresence(t, watcher)
+	if len(events) != 1 || events[0].Status != StatusOnline || events[0].Data.UserId != "a" {
+		t.Fatalf("after two connections entered got %+v, want one online event", events)
+	}
+
+	watcher.Reset()
+	h.RemoveFromRoom("r", a1)
+	if events := roomPresence(t, watcher); len(events) != 0 {
+		t.Fatalf("leaving with a connection still subscribed sent %+v", events)
+	}
+	h.Remove(a2)
+	events = roomPresence(t, watcher)
+	if len(events) != 1 || events[0].Status != StatusOffline {
+		t.Fatalf("after last connection left got %+v, want one offline event", events)
+	}
+}
+
+func TestAddAndRemoveUserFromRoom(t *testing.T) {
+	h := NewHub()
+	a1 := connect(h, "a")
+	a2 := connect(h, "a")
+	b := connect(h, "b")
+	h.AddToRoom("r", b)
+
+	h.AddUserToRoom("r", "a")
+	a1.Reset()
+	a2.Reset()
+	h.BroadcastToRoom("r", []byte(`{}`))
+	if len(a1.Sent()) != 1 || len(a2.Sent()) != 1 {
+		t.Fatal("AddUserToRoom did not subscribe every connection")
+	}
+
+	b.Reset()
+	h.RemoveUserFromRoom("r", "a")
+	a1.Reset()
+	h.BroadcastToRoom("r", []byte(`{}`))
+	if len(a1.Sent()) != 0 {
+		t.Fatal("RemoveUserFromRoom left a connection subscribed")
+	}
+	events := roomPresence(t, b)
+	if len(events) != 1 || events[0].Status != StatusOffline {
+		t.Fatalf("room got %+v, want one offline event", events)
+	}
+}
+
+func TestBroadcastToRoomAndUsersSendsOncePerConnection(t *testing.T) {
+	h := NewHub()
+	a := connect(h, "a")
+	b := connect(h, "b")
+	h.AddToRoom("r", a)
+	a.Reset()
+	b.Reset()
+
+	h.BroadcastToRoomAndUsers("r", []string{"a", "b"}, []byte(`{}`))
+
+	if len(a.Sent()) != 1 || len(b.Sent()) != 1 {
+		t.Fatalf("got %d and %d payloads, want 1 each", len(a.Sent()), len(b.Sent()))
+	}
+}
+
+func TestAppearOfflineHidesPresence(t *testing.T) {
+	h := NewHub()
+	watcher := connect(h, "w")
+	h.AddToRoom("r", watcher)
+	h.SetAppearOffline("a", true)
+	a := connect(h, "a")
+	watcher.Reset()
+
+	h.AddToRoom("r", a)
+	if events := roomPresence(t, watcher); len(events) != 0 {
+		t.Fatalf("hidden user announced %+v", events)
+	}
+	if h.AppearsOnline("a") || len(h.VisibleOnlineUsers([]string{"a"})) != 0 {
+		t.Fatal("hidden user is visible")
+	}
+
+	if !h.SetAppearOffline("a", false) {
+		t.Fatal("revealing did not report a change")
+	}
+	events := roomPresence(t, watcher)
+	if len(events) != 1 || events[0].Status != StatusOnline {
+		t.Fatalf("revealing sent %+v, want one online event", events)
+	}
+	if h.SetAppearOffline("a", false) {
+		t.Fatal("setting the same value reported a change")
+	}
+}
+
+func TestOnlineCountSkipsHiddenUsers(t *testing.T) {
+	h := NewHub()
+	connect(h, "a")
+	connect(h, "b")
+	h.SetAppearOffline("b", true)
+
+	if got := h.OnlineCount([]string{"a", "b", "c"}); got != 1 {
+		t.Fatalf("OnlineCount = %d, want 1", got)
+	}
+	h.SetAppearOffline("b", false)
+	if got := h.OnlineCount([]string{"a", "b", "c"}); got != 2 {
+		t.Fatalf("OnlineCount after revealing = %d, want 2", got)
+	}
+}
+
+func TestIdleSweepMarksAwayAndTouchRestores(t *testing.T) {
+	h := NewHub()
+	watcher := connect(h, "w")
+	h.AddToRoom("r", watcher)
+	a := connect(h, "a")
+	h.AddToRoom("r", a)
+	watcher.Reset()
+
+	now := time.Now()
+	h.Touch(watcher, now.Add(time.Hour))
+	changed := h.SweepIdle(now.Add(time.Hour), time.Minute)
+	if len(changed) != 1 || changed[0].UserId != "a" || changed[0].Status != StatusAway {
+		t.Fatalf("sweep changed %+v, want a away", changed)
+	}
+	events := roomPresence(t, watcher)
+	if len(events) != 1 || events[0].Status != StatusAway {
+		t.Fatalf("room got %+v, want one away event", events)
+	}
+
+	info, changedBack := h.Touch(a, now.Add(time.Hour))
+	if !changedBack || info.Status != StatusOnline {
+		t.Fatalf("Touch = %+v, %v; want online, true", info, changedBack)
+	}
+}
+
+func TestChosenStatusSurvivesIdleAndExpires(t *testing.T) {
+	h := NewHub()
+	a := connect(h, "a")
+	expires := time.Now().Add(time.Minute)
+
+	info, changed := h.SetStatus("a", StatusBusy, "in a meeting", &expires)
+	if !changed || info.Status != StatusBusy || info.StatusText != "in a meeting" {
+		t.Fatalf("SetStatus = %+v, %v", info, changed)
+	}
+
+	// Idle users keep an explicit busy status.
+	later := time.Now().Add(30 * time.Second)
+	h.Touch(a, time.Now().Add(-time.Hour))
+	if changed := h.SweepIdle(later, time.Second); len(changed) != 0 {
+		t.Fatalf("idle busy user changed to %+v", changed)
+	}
+
+	changed2 := h.SweepIdle(expires.Add(time.Second), time.Second)
+	if len(changed2) != 1 || changed2[0].Status != StatusAway || changed2[0].StatusText != "" {
+		t.Fatalf("after expiry got %+v, want away with no text", changed2)
+	}
+}
+
+func TestCloseSessionClosesOnlyThatConnection(t *testing.T) {
+	h := NewHub()
+	a1 := connect(h, "a")
+	a2 := connect(h, "a")
+
+	if h.CloseSession("b", a1.Session().Id) {
+		t.Fatal("closed another user's session")
+	}
+	if !h.CloseSession("a", a1.Session().Id) {
+		t.Fatal("session not found")
+	}
+	if closed, code := a1.Closed(); !closed || code != CloseSessionTerminated {
+		t.Fatalf("a1 closed=%v code=%d", closed, code)
+	}
+	if closed, _ := a2.Closed(); closed {
+		t.Fatal("other session closed too")
+	}
+	if h.FindSession("a", a2.Session().Id) == nil {
+		t.Fatal("FindSession lost the remaining session")
+	}
+}
+
+func TestFailingConnectionDoesNotBlockOthers(t *testing.T) {
+	h := NewHub()
+	bad := connect(h, "bad")
+	good := connect(h, "good")
+	h.AddToRoom("r", bad)
+	h.AddToRoom("r", good)
+	bad.FailSends(fmt.Errorf("broken pipe"))
+	good.Reset()
+
+	h.BroadcastToRoom("r", []byte(`{}`))
+	if len(good.Sent()) != 1 {
+		t.Fatal("healthy connection missed the broadcast")
+	}
+}
+
+func TestConcurrentJoinLeaveAndBroadcast(t *testing.T) {
+	h := NewHub()
+	const (
+		users     = 20
+		rooms     = 5
+		rounds    = 50
+		listeners = 5
+	)
+
+	// Listeners stay in every room for the whole test and must receive
+	// every broadcast, however much churn happens around them.
+	stable := make([]*FakeConn, listeners)
+	for i := range stable {
+		stable[i] = connect(h, fmt.Sprintf("listener-%d", i))
+		for r := 0; r < rooms; r++ {
+			h.AddToRoom(fmt.Sprintf("room-%d", r), stable[i])
+		}
+		stable[i].Reset()
+	}
+
+	var wg sync.WaitGroup
+	for u := 0; u < users; u++ {
+		wg.Add(1)
+		go func(u int) {
+			defer wg.Done()
+			userId := fmt.Sprintf("user-%d", u)
+			for i := 0; i < rounds; i++ {
+				conn := connect(h, userId)
+				room := fmt.Sprintf("room-%d", (u+i)%rooms)
+				h.AddToRoom(room, conn)
+				h.Touch(conn, time.Now())
+				h.AddUserToRoom(fmt.Sprintf("room-%d", i%rooms), userId)
+				h.RemoveFromRoom(room, conn)
+				h.RemoveUserFromRoom(fmt.Sprintf("room-%d", i%rooms), userId)
+				h.Remove(conn)
+			}
+		}(u)
+	}
+
+	const broadcasts = 200
+	for b := 0; b < 4; b++ {
+		wg.Add(1)
+		go func(b int) {
+			defer wg.Done()
+			for i := 0; i < broadcasts/4; i++ {
+				h.BroadcastToRoom(fmt.Sprintf("room-%d", i%rooms), []byte(`{"type":"message"}`))
+				h.SendToUsers([]string{"user-1", "user-2"}, []byte(`{}`))
+				h.SweepIdle(time.Now(), time.Hour)
+				_ = h.VisibleOnlineUsers([]string{"user-3"})
+				_ = h.Sessions("user-4")
+			}
+		}(b)
+	}
+	wg.Wait()
+
+	for i, conn := range stable {
+		if got := len(conn.OfType(TypeTextMessage)); got != broadcasts {
+			t.Errorf("listener %d got %d broadcasts, want %d", i, got, broadcasts)
+		}
+	}
+	for u := 0; u < users; u++ {
+		if h.IsOnline(fmt.Sprintf("user-%d", u)) {
+			t.Errorf("user-%d still online after every connection left", u)
+		}
+	}
+	if got := h.OnlineCount([]string{"listener-0", "user-0"}); got != 1 {
+		t.Errorf("OnlineCount = %d, want 1", got)
+	}
+}
@@ -0,0 +1,333 @@
+package websocket
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AnyAction keys the limit every incoming envelope counts against, whatever
+// its type, including ones that fail to decode.
+const AnyAction MessageType = "*"
+
+// CloseRateLimited is the close code sent when a connection is cut off for
+// repeatedly exceeding its rate limits. It mirrors HTTP 429.
+const CloseRateLimited = 4029
+
+const (
+	ScopeConnection = "connection"
+	ScopeUser       = "user"
+)
+
+// Rate is a token bucket: Burst actions at once, refilled at PerSecond. The
+// zero Rate means unlimited.
+type Rate struct {
+	PerSecond float64
+	Burst     int
+}
+
+func (r Rate) unlimited() bool {
+	return r.PerSecond <= 0 || r.Burst <= 0
+}
+
+// RateLimits configures how fast clients may send each action type.
+type RateLimits struct {
+	Enabled       bool
+	PerConnection map[MessageType]Rate
+	PerUser       map[MessageType]Rate
+	// Violations is how many rejected actions a user may rack up before being
+	// disconnected; it refills like any other bucket.
+	Violations Rate
+	// Cooldown is how long a disconnected user is refused new connections.
+	Cooldown time.Duration
+}
+
+// DefaultRateLimits are generous enough for people typing and reacting but
+// stop scripted floods of writes that hit the database.
+func DefaultRateLimits() RateLimits {
+	return RateLimits{
+		Enabled: true,
+		PerConnection: map[MessageType]Rate{
+			AnyAction:        {PerSecond: 20, Burst: 40},
+			TypeTextMessage:  {PerSecond: 5, Burst: 10},
+			TypeReactMessage: {PerSecond: 10, Burst: 20},
+			TypeCreateRoom:   {PerSecond: 0.1, Burst: 3},
+			TypeJoinRoom:     {PerSecond: 2, Burst: 10},
+			TypeSubscribe:    {PerSecond: 5, Burst: 20},
+			TypeUnsubscribe:  {PerSecond: 5, Burst: 20},
+		},
+		PerUser: map[MessageType]Rate{
+			AnyAction:        {PerSecond: 40, Burst: 80},
+			TypeTextMessage:  {PerSecond: 8, Burst: 16},
+			TypeReactMessage: {PerSecond: 15, Burst: 30},
+			TypeCreateRoom:   {PerSecond: 0.2, Burst: 5},
+		},
+		Violations: Rate{PerSecond: 10.0 / 60, Burst: 10},
+		Cooldown:   time.Minute,
+	}
+}
+
+// ParseRateLimits applies overrides to base. The spec is a comma-separated
+// list of action.scope=rate/burst, for example
+// "message.connection=2/5,react_message.user=0/0"; a zero rate lifts the
+// limit.
+func ParseRateLimits(base RateLimits, spec string) (RateLimits, error) {
+	limits := base
+	limits.PerConnection = cloneRates(base.PerConnection)
+	limits.PerUser = cloneRates(base.PerUser)
+
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return base, fmt.Errorf("rate limit %q: missing '='", item)
+		}
+		action, scope, ok := strings.Cut(key, ".")
+		if !ok || action == "" {
+			return base, fmt.Errorf("rate limit %q: want action.scope", item)
+		}
+		perSecond, burst, ok := strings.Cut(value, "/")
+		if !ok {
+			return base, fmt.Errorf("rate limit %q: want rate/burst", item)
+		}
+		var rate Rate
+		var err error
+		if rate.PerSecond, err = strconv.ParseFloat(perSecond, 64); err != nil || rate.PerSecond < 0 {
+			return base, fmt.Errorf("rate limit %q: invalid rate", item)
+		}
+		if rate.Burst, err = strconv.Atoi(burst); err != nil || rate.Burst < 0 {
+			return base, fmt.Errorf("rate limit %q: invalid burst", item)
+		}
+
+		switch scope {
+		case ScopeConnection:
+			limits.PerConnection[MessageType(action)] = rate
+		case ScopeUser:
+			limits.PerUser[MessageType(action)] = rate
+		default:
+			return base, fmt.Errorf("rate limit %q: scope must be %s or %s", item, ScopeConnection, ScopeUser)
+		}
+	}
+	return limits, nil
+}
+
+func cloneRates(rates map[MessageType]Rate) map[MessageType]Rate {
+	cloned := make(map[MessageType]Rate, len(rates))
+	for action, rate := range rates {
+		cloned[action] = rate
+	}
+	return cloned
+}
+
+// RateLimitError is returned for an action over its limit. Disconnect is set
+// once the user has been rejected too often and is being cut off.
+type RateLimitError struct {
+	Action     MessageType
+	Scope      string
+	RetryAfter time.Duration
+	Disconnect bool
+}
+
+func (e *RateLimitError) Error() string {
+	if e.Disconnect {
+		return fmt.Sprintf("too many requests: disconnected, retry in %s", e.RetryAfter.Round(time.Second))
+	}
+	return fmt.Sprintf("too many %s requests per %s: retry in %s", e.Action, e.Scope, e.RetryAfter.Round(time.Millisecond))
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(r Rate, now time.Time) *bucket {
+	return &bucket{tokens: float64(r.Burst), last: now}
+}
+
+func (b *bucket) refill(r Rate, now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(r.Burst), b.tokens+elapsed*r.PerSecond)
+		b.last = now
+	}
+}
+
+// wait is how long until the bucket holds a whole token.
+func (b *bucket) wait(r Rate) time.Duration {
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / r.PerSecond * float64(time.Second))
+}
+
+type userLimits struct {
+	buckets      map[MessageType]*bucket
+	violations   *bucket
+	blockedUntil time.Time
+}
+
+// RateLimiter enforces RateLimits for connections and the users behind them.
+// It is safe for concurrent use.
+type RateLimiter struct {
+	limits RateLimits
+
+	mu    sync.Mutex
+	conns map[Conn]map[MessageType]*bucket
+	users map[string]*userLimits
+}
+
+func NewRateLimiter(limits RateLimits) *RateLimiter {
+	return &RateLimiter{
+		limits: limits,
+		conns:  make(map[Conn]map[MessageType]*bucket),
+		users:  make(map[string]*userLimits),
+	}
+}
+
+// Allow charges one action to the connection and its user, returning a
+// *RateLimitError if any of their buckets is empty. Nothing is charged for a
+// rejected action, but the rejection counts as a violation.
+func (l *RateLimiter) Allow(conn Conn, userId string, action MessageType, now time.Time) *RateLimitError {
+	if !l.limits.Enabled {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	u := l.userLocked(userId)
+	if now.Before(u.blockedUntil) {
+		return &RateLimitError{Action: action, Scope: ScopeUser, RetryAfter: u.blockedUntil.Sub(now), Disconnect: true}
+	}
+
+	connBuckets := l.conns[conn]
+	if connBuckets == nil {
+		connBuckets = make(map[MessageType]*bucket)
+		l.conns[conn] = connBuckets
+	}
+
+	type charge struct {
+		b *bucket
+		r Rate
+	}
+	var charges []charge
+	var rejected *RateLimitError
+	check := func(buckets map[MessageType]*bucket, rates map[MessageType]Rate, key MessageType, scope string) {
+		r, ok := rates[key]
+		if !ok || r.unlimited() {
+			return
+		}
+		b := buckets[key]
+		if b == nil {
+			b = newBucket(r, now)
+			buckets[key] = b
+		}
+		b.refill(r, now)
+		if wait := b.wait(r); wait > 0 {
+			if rejected == nil || wait > rejected.RetryAfter {
+				rejected = &RateLimitError{Action: action, Scope: scope, RetryAfter: wait}
+			}
+			return
+		}
+		charges = append(charges, charge{b, r})
+	}
+
+	keys := []MessageType{AnyAction}
+	if action != AnyAction && action != "" {
+		keys = append(keys, action)
+	}
+	for _, key := range keys {
+		check(connBuckets, l.limits.PerConnection, key, ScopeConnection)
+		check(u.buckets, l.limits.PerUser, key, ScopeUser)
+	}
+
+	if rejected == nil {
+		for _, c := range charges {
+			c.b.tokens--
+		}
+		return nil
+	}
+
+	if l.limits.Violations.unlimited() {
+		return rejected
+	}
+	if u.violations == nil {
+		u.violations = newBucket(l.limits.Violations, now)
+	}
+	u.violations.refill(l.limits.Violations, now)
+	u.violations.tokens--
+	if u.violations.tokens < 0 {
+		u.blockedUntil = now.Add(l.limits.Cooldown)
+		u.violations = nil
+		rejected.Disconnect = true
+		rejected.RetryAfter = l.limits.Cooldown
+	}
+	return rejected
+}
+
+// Blocked reports whether the user was disconnected for abuse and for how
+// much longer new connections should be refused.
+func (l *RateLimiter) Blocked(userId string, now time.Time) (time.Duration, bool) {
+	if !l.limits.Enabled {
+		return 0, false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	u, ok := l.users[userId]
+	if !ok || !now.Before(u.blockedUntil) {
+		return 0, false
+	}
+	return u.blockedUntil.Sub(now), true
+}
+
+// Forget drops a closed connection's buckets.
+func (l *RateLimiter) Forget(conn Conn) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.conns, conn)
+}
+
+// Prune drops per-user state that has fully recovered, so users who have
+// gone quiet do not hold memory.
+func (l *RateLimiter) Prune(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for userId, u := range l.users {
+		if now.Before(u.blockedUntil) || !recovered(u.violations, l.limits.Violations, now) {
+			continue
+		}
+		full := true
+		for action, b := range u.buckets {
+			if !recovered(b, l.limits.PerUser[action], now) {
+				full = false
+				break
+			}
+		}
+		if full {
+			delete(l.users, userId)
+		}
+	}
+}
+
+func recovered(b *bucket, r Rate, now time.Time) bool {
+	if b == nil {
+		return true
+	}
+	b.refill(r, now)
+	return b.tokens >= float64(r.Burst)
+}
+
+func (l *RateLimiter) userLocked(userId string) *userLimits {
+	u, ok := l.users[userId]
+	if !ok {
+		u = &userLimits{buckets: make(map[MessageType]*bucket)}
+		l.users[userId] = u
+	}
+	return u
+}
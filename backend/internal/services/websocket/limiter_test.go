@@ -0,0 +1,142 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+)
+
+func testLimits() RateLimits {
+	return RateLimits{
+		Enabled: true,
+		PerConnection: map[MessageType]Rate{
+			TypeTextMessage: {PerSecond: 1, Burst: 2},
+		},
+		PerUser: map[MessageType]Rate{
+			TypeTextMessage: {PerSecond: 1, Burst: 3},
+		},
+		Violations: Rate{PerSecond: 0.1, Burst: 2},
+		Cooldown:   time.Minute,
+	}
+}
+
+func TestRateLimiterRefillsPerConnection(t *testing.T) {
+	l := NewRateLimiter(testLimits())
+	conn := NewFakeConn("test")
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if err := l.Allow(conn, "a", TypeTextMessage, now); err != nil {
+			t.Fatalf("message %d rejected: %v", i, err)
+		}
+	}
+	err := l.Allow(conn, "a", TypeTextMessage, now)
+	if err == nil || err.Scope != ScopeConnection || err.Disconnect {
+		t.Fatalf("third message: got %+v, want connection limit", err)
+	}
+	if err.RetryAfter <= 0 || err.RetryAfter > time.Second {
+		t.Fatalf("RetryAfter = %s, want within a second", err.RetryAfter)
+	}
+	if err := l.Allow(conn, "a", TypeReactMessage, now); err != nil {
+		t.Fatalf("unlimited action rejected: %v", err)
+	}
+	if err := l.Allow(conn, "a", TypeTextMessage, now.Add(time.Second)); err != nil {
+		t.Fatalf("message after refill rejected: %v", err)
+	}
+}
+
+func TestRateLimiterSharesUserBucketAcrossConnections(t *testing.T) {
+	l := NewRateLimiter(testLimits())
+	phone := NewFakeConn("phone")
+	laptop := NewFakeConn("laptop")
+	now := time.Now()
+
+	for _, conn := range []*FakeConn{phone, phone, laptop} {
+		if err := l.Allow(conn, "a", TypeTextMessage, now); err != nil {
+			t.Fatalf("rejected early: %v", err)
+		}
+	}
+	err := l.Allow(laptop, "a", TypeTextMessage, now)
+	if err == nil || err.Scope != ScopeUser {
+		t.Fatalf("got %+v, want user limit", err)
+	}
+	if err := l.Allow(NewFakeConn("other"), "b", TypeTextMessage, now); err != nil {
+		t.Fatalf("other user rejected: %v", err)
+	}
+}
+
+func TestRateLimiterDisconnectsRepeatOffenders(t *testing.T) {
+	l := NewRateLimiter(testLimits())
+	conn := NewFakeConn("test")
+	now := time.Now()
+
+	var last *RateLimitError
+	for i := 0; i < 5 && (last == nil || !last.Disconnect); i++ {
+		last = l.Allow(conn, "a", TypeTextMessage, now)
+	}
+	if last == nil || !last.Disconnect {
+		t.Fatal("repeated violations did not disconnect")
+	}
+	if wait, blocked := l.Blocked("a", now); !blocked || wait != time.Minute {
+		t.Fatalf("Blocked = %s, %v; want 1m, true", wait, blocked)
+	}
+
+	l.Forget(conn)
+	if err := l.Allow(NewFakeConn("test"), "a", TypeReactMessage, now.Add(time.Second)); err == nil || !err.Disconnect {
+		t.Fatalf("blocked user allowed on a new connection: %+v", err)
+	}
+	if _, blocked := l.Blocked("a", now.Add(time.Minute)); blocked {
+		t.Fatal("still blocked after the cooldown")
+	}
+}
+
+func TestRateLimiterPruneKeepsBlockedUsers(t *testing.T) {
+	l := NewRateLimiter(testLimits())
+	now := time.Now()
+	l.Allow(NewFakeConn("test"), "quiet", TypeTextMessage, now)
+	for i := 0; i < 6; i++ {
+		l.Allow(NewFakeConn("test"), "noisy", TypeTextMessage, now)
+	}
+
+	l.Prune(now.Add(10 * time.Second))
+	if _, ok := l.users["quiet"]; ok {
+		t.Fatal("recovered user not pruned")
+	}
+	if _, blocked := l.Blocked("noisy", now.Add(10*time.Second)); !blocked {
+		t.Fatal("blocked user pruned")
+	}
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	limits := testLimits()
+	limits.Enabled = false
+	l := NewRateLimiter(limits)
+	conn := NewFakeConn("test")
+	for i := 0; i < 10; i++ {
+		if err := l.Allow(conn, "a", TypeTextMessage, time.Now()); err != nil {
+			t.Fatalf("disabled limiter rejected: %v", err)
+		}
+	}
+}
+
+func TestParseRateLimits(t *testing.T) {
+	base := DefaultRateLimits()
+	limits, err := ParseRateLimits(base, " message.connection=2/5, react_message.user=0/0 ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := limits.PerConnection[TypeTextMessage]; got != (Rate{PerSecond: 2, Burst: 5}) {
+		t.Fatalf("message.connection = %+v", got)
+	}
+	if !limits.PerUser[TypeReactMessage].unlimited() {
+		t.Fatal("react_message.user not lifted")
+	}
+	if base.PerConnection[TypeTextMessage] == limits.PerConnection[TypeTextMessage] {
+		t.Fatal("overrides leaked into the base limits")
+	}
+
+	for _, spec := range []string{"message", "message=1/1", "message.room=1/1", "message.user=x/1", "message.user=1/-1"} {
+		if _, err := ParseRateLimits(base, spec); err == nil {
+			t.Fatalf("ParseRateLimits(%q) succeeded", spec)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package websocket
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// A small MessagePack implementation covering what JSON can express: nil,
+// booleans, numbers, strings, arrays and string-keyed maps. Binary values
+// decode as strings; extension types are rejected.
+
+// maxMsgpackDepth bounds array and map nesting. Envelopes nest a few levels
+// deep; without a bound a frame of nested one-element arrays recurses until
+// the stack overflows.
+const maxMsgpackDepth = 32
+
+var (
+	errMsgpackShort    = errors.New("msgpack: unexpected end of data")
+	errMsgpackTrailing = errors.New("msgpack: trailing data after value")
+	errMsgpackDepth    = errors.New("msgpack: nesting too deep")
+)
+
+func appendMsgpack(b []byte, v any) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(b, 0xc0), nil
+	case bool:
+		if v {
+			return append(b, 0xc3), nil
+		}
+		return append(b, 0xc2), nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return appendMsgpackInt(b, i), nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return appendMsgpackFloat(b, f), nil
+	case float64:
+		return appendMsgpackFloat(b, v), nil
+	case string:
+		return appendMsgpackString(b, v), nil
+	case []any:
+		b = appendMsgpackHeader(b, len(v), 0x90, 0xdc, 0xdd)
+		var err error
+		for _, item := range v {
+			if b, err = appendMsgpack(b, item); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b = appendMsgpackHeader(b, len(v), 0x80, 0xde, 0xdf)
+		var err error
+		for _, k := range keys {
+			b = appendMsgpackString(b, k)
+			if b, err = appendMsgpack(b, v[k]); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("msgpack: unsupported type %T", v)
+}
+
+func appendMsgpackInt(b []byte, i int64) []byte {
+	switch {
+	case i >= 0 && i <= 0x7f:
+		return append(b, byte(i))
+	case i < 0 && i >= -32:
+		return append(b, byte(int8(i)))
+	case i >= math.MinInt8 && i <= math.MaxInt8:
+		return append(b, 0xd0, byte(int8(i)))
+	case i >= math.MinInt16 && i <= math.MaxInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(int16(i)))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(int32(i)))
+	}
+	return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(i))
+}
+
+func appendMsgpackFloat(b []byte, f float64) []byte {
+	return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(f))
+}
+
+func appendMsgpackString(b []byte, s string) []byte {
+	n := len(s)
+	switch {
+	case n <= 31:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+	}
+	return append(b, s...)
+}
+
+// appendMsgpackHeader writes an array or map length using the fix, 16-bit or
+// 32-bit form.
+func appendMsgpackHeader(b []byte, n int, fix, code16, code32 byte) []byte {
+	switch {
+	case n <= 15:
+		return append(b, fix|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, code16), uint16(n))
+	}
+	return binary.BigEndian.AppendUint32(append(b, code32), uint32(n))
+}
+
+// readMsgpack decodes one value nested depth levels deep and returns the
+// remaining bytes.
+func readMsgpack(b []byte, depth int) (any, []byte, error) {
+	if depth > maxMsgpackDepth {
+		return nil, nil, errMsgpackDepth
+	}
+	if len(b) == 0 {
+		return nil, nil, errMsgpackShort
+	}
+	c, b := b[0], b[1:]
+
+	switch {
+	case c <= 0x7f:
+		return int64(c), b, nil
+	case c >= 0xe0:
+		return int64(int8(c)), b, nil
+	case c&0xe0 == 0xa0:
+		return readMsgpackString(b, int(c&0x1f))
+	case c&0xf0 == 0x90:
+		return readMsgpackArray(b, int(c&0x0f), depth)
+	case c&0xf0 == 0x80:
+		return readMsgpackMap(b, int(c&0x0f), depth)
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, b, nil
+	case 0xc2:
+		return false, b, nil
+	case 0xc3:
+		return true, b, nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		size := 1 << (c - 0xcc)
+		u, rest, err := readMsgpackUint(b, size)
+		if err != nil {
+			return nil, nil, err
+		}
+		if u > math.MaxInt64 {
+			return float64(u), rest, nil
+		}
+		return int64(u), rest, nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (c - 0xd0)
+		u, rest, err := readMsgpackUint(b, size)
+		if err != nil {
+			return nil, nil, err
+		}
+		shift := 64 - 8*size
+		return int64(u<<shift) >> shift, rest, nil
+	case 0xca:
+		u, rest, err := readMsgpackUint(b, 4)
+		return float64(math.Float32frombits(uint32(u))), rest, err
+	case 0xcb:
+		u, rest, err := readMsgpackUint(b, 8)
+		return math.Float64frombits(u), rest, err
+	case 0xd9, 0xda, 0xdb, 0xc4, 0xc5, 0xc6:
+		size := 1
+		switch c {
+		case 0xda, 0xc5:
+			size = 2
+		case 0xdb, 0xc6:
+			size = 4
+		}
+		n, rest, err := readMsgpackUint(b, size)
+		if err != nil {
+			return nil, nil, err
+		}
+		return readMsgpackString(rest, int(n))
+	case 0xdc, 0xdd:
+		n, rest, err := readMsgpackUint(b, 2+2*int(c-0xdc))
+		if err != nil {
+			return nil, nil, err
+		}
+		return readMsgpackArray(rest, int(n), depth)
+	case 0xde, 0xdf:
+		n, rest, err := readMsgpackUint(b, 2+2*int(c-0xde))
+		if err != nil {
+			return nil, nil, err
+		}
+		return readMsgpackMap(rest, int(n), depth)
+	}
+	return nil, nil, fmt.Errorf("msgpack: unsupported type byte 0x%02x", c)
+}
+
+func readMsgpackUint(b []byte, size int) (uint64, []byte, error) {
+	if len(b) < size {
+		return 0, nil, errMsgpackShort
+	}
+	var u uint64
+	for _, x := range b[:size] {
+		u = u<<8 | uint64(x)
+	}
+	return u, b[size:], nil
+}
+
+func readMsgpackString(b []byte, n int) (any, []byte, error) {
+	if n < 0 || len(b) < n {
+		return nil, nil, errMsgpackShort
+	}
+	return string(b[:n]), b[n:], nil
+}
+
+func readMsgpackArray(b []byte, n, depth int) (any, []byte, error) {
+	// Every element takes at least one byte, which bounds n by the input.
+	if n > len(b) {
+		return nil, nil, errMsgpackShort
+	}
+	items := make([]any, 0, n)
+	for i := 0; i < n; i++ {
+		var item any
+		var err error
+		if item, b, err = readMsgpack(b, depth+1); err != nil {
+			return nil, nil, err
+		}
+		items = append(items, item)
+	}
+	return items, b, nil
+}
+
+func readMsgpackMap(b []byte, n, depth int) (any, []byte, error) {
+	if 2*n > len(b) {
+		return nil, nil, errMsgpackShort
+	}
+	m := make(map[string]any, n)
+	for i := 0; i < n; i++ {
+		key, rest, err := readMsgpack(b, depth+1)
+		if err != nil {
+			return nil, nil, err
+		}
+		k, ok := key.(string)
+		if !ok {
+			return nil, nil, errors.New("msgpack: map keys must be strings")
+		}
+		var value any
+		if value, b, err = readMsgpack(rest, depth+1); err != nil {
+			return nil, nil, err
+		}
+		m[k] = value
+	}
+	return m, b, nil
+}
@@ -0,0 +1,176 @@
+package websocket
+
+import "time"
+
+// presenceLocked returns the user's presence with their effective status.
+// Callers must hold h.mu.
+func (h *Hub) presenceLocked(userId string) UserPresenceData {
+	info := h.userInfo[userId]
+	if info.Status == "" {
+		info.Status = StatusOnline
+	}
+	if _, idle := h.idle[userId]; idle && info.Status == StatusOnline {
+		info.Status = StatusAway
+	}
+	return info
+}
+
+// Presence returns the user's presence with their effective status and
+// whether they are connected.
+func (h *Hub) Presence(userId string) (UserPresenceData, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if len(h.users[userId]) == 0 {
+		return UserPresenceData{}, false
+	}
+	return h.presenceLocked(userId), true
+}
+
+// VisibleUserIDs returns every connected user that is not appearing offline.
+func (h *Hub) VisibleUserIDs() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	ids := make([]string, 0, len(h.users))
+	for userId := range h.users {
+		if _, hidden := h.hidden[userId]; !hidden {
+			ids = append(ids, userId)
+		}
+	}
+	return ids
+}
+
+// userRoomsLocked returns the rooms any of the user's connections is
+// subscribed to. Callers must hold h.mu.
+func (h *Hub) userRoomsLocked(userId string) []string {
+	var rooms []string
+	for roomId := range h.rooms {
+		if h.userInRoomLocked(roomId, userId) {
+			rooms = append(rooms, roomId)
+		}
+	}
+	return rooms
+}
+
+func presenceChanged(before, after UserPresenceData) bool {
+	return before.Status != after.Status || before.StatusText != after.StatusText
+}
+
+// Touch records activity on the connection. It reports whether this brought
+// the user back from idle in a way others can see; rooms are told directly
+// and the caller is left to tell contacts.
+func (h *Hub) Touch(conn Conn, now time.Time) (UserPresenceData, bool) {
+	h.mu.Lock()
+
+	userId, ok := h.connUser[conn]
+	if !ok {
+		h.mu.Unlock()
+		return UserPresenceData{}, false
+	}
+	h.lastActive[conn] = now
+	if _, idle := h.idle[userId]; !idle {
+		h.mu.Unlock()
+		return UserPresenceData{}, false
+	}
+
+	before := h.presenceLocked(userId)
+	delete(h.idle, userId)
+	after := h.presenceLocked(userId)
+	_, hidden := h.hidden[userId]
+	changed := !hidden && presenceChanged(before, after)
+	var rooms []string
+	if changed {
+		rooms = h.userRoomsLocked(userId)
+	}
+	h.mu.Unlock()
+
+	for _, roomId := range rooms {
+		h.broadcastRoomPresence(roomId, after, after.Status)
+	}
+	return after, changed
+}
+
+// SetStatus replaces the chosen status of an online user. It reports whether
+// the visible presence changed.
+func (h *Hub) SetStatus(userId string, status UserStatus, text string, expiresAt *time.Time) (UserPresenceData, bool) {
+	h.mu.Lock()
+
+	if len(h.users[userId]) == 0 {
+		h.mu.Unlock()
+		return UserPresenceData{}, false
+	}
+
+	before := h.presenceLocked(userId)
+	info := h.userInfo[userId]
+	info.Status = status
+	info.StatusText = text
+	info.StatusExpiresAt = expiresAt
+	h.userInfo[userId] = info
+	after := h.presenceLocked(userId)
+
+	_, hidden := h.hidden[userId]
+	changed := !hidden && presenceChanged(before, after)
+	var rooms []string
+	if changed {
+		rooms = h.userRoomsLocked(userId)
+	}
+	h.mu.Unlock()
+
+	for _, roomId := range rooms {
+		h.broadcastRoomPresence(roomId, after, after.Status)
+	}
+	return after, changed
+}
+
+// SweepIdle expires custom statuses and marks users idle once every one of
+// their connections has been quiet for idleAfter. It returns the users whose
+// visible presence changed; rooms are told directly.
+func (h *Hub) SweepIdle(now time.Time, idleAfter time.Duration) []UserPresenceData {
+	type change struct {
+		info  UserPresenceData
+		rooms []string
+	}
+
+	h.mu.Lock()
+	var changes []change
+	for userId, conns := range h.users {
+		before := h.presenceLocked(userId)
+
+		info := h.userInfo[userId]
+		if info.StatusExpiresAt != nil && !info.StatusExpiresAt.After(now) {
+			info.Status = StatusOnline
+			info.StatusText = ""
+			info.StatusExpiresAt = nil
+			h.userInfo[userId] = info
+		}
+
+		idle := true
+		for c := range conns {
+			if now.Sub(h.lastActive[c]) < idleAfter {
+				idle = false
+				break
+			}
+		}
+		if idle {
+			h.idle[userId] = struct{}{}
+		} else {
+			delete(h.idle, userId)
+		}
+
+		after := h.presenceLocked(userId)
+		if _, hidden := h.hidden[userId]; hidden || !presenceChanged(before, after) {
+			continue
+		}
+		changes = append(changes, change{info: after, rooms: h.userRoomsLocked(userId)})
+	}
+	h.mu.Unlock()
+
+	result := make([]UserPresenceData, 0, len(changes))
+	for _, ch := range changes {
+		for _, roomId := range ch.rooms {
+			h.broadcastRoomPresence(roomId, ch.info, ch.info.Status)
+		}
+		result = append(result, ch.info)
+	}
+	return result
+}
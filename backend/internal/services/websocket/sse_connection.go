@@ -0,0 +1,107 @@
+package websocket
+
+import (
+	"errors"
+	"net"
+	"sync"
+)
+
+const sseBufferSize = 256
+
+var (
+	// ErrNotDeliverable is returned when actions are posted to a connection
+	// whose transport reads them itself.
+	ErrNotDeliverable = errors.New("connection does not accept posted actions")
+	errSSESlowClient  = errors.New("sse client is not keeping up")
+)
+
+// sseTransport backs a Server-Sent Events stream. Outgoing envelopes are
+// queued for the HTTP response writer; incoming ones arrive through REST and
+// are queued for the connection's read loop.
+type sseTransport struct {
+	in     chan []byte
+	out    chan []byte
+	closed chan struct{}
+	once   sync.Once
+}
+
+// SSEStream is the response side of an SSE connection.
+type SSEStream struct {
+	t *sseTransport
+}
+
+// NewSSEConnection creates a connection whose envelopes are written to the
+// returned stream and whose actions are posted with Deliver.
+func NewSSEConnection(device string, userAgent string) (*Connection, *SSEStream) {
+	t := &sseTransport{
+		in:     make(chan []byte, sseBufferSize),
+		out:    make(chan []byte, sseBufferSize),
+		closed: make(chan struct{}),
+	}
+	conn := newConnection(t, SessionInfo{
+		Device:    device,
+		UserAgent: userAgent,
+		Transport: "sse",
+	})
+	return conn, &SSEStream{t: t}
+}
+
+// Messages yields the envelopes to write as events.
+func (s *SSEStream) Messages() <-chan []byte {
+	return s.t.out
+}
+
+// Done is closed once the connection is closed from either side.
+func (s *SSEStream) Done() <-chan struct{} {
+	return s.t.closed
+}
+
+func (t *sseTransport) ReadMessage() ([]byte, error) {
+	select {
+	case msg := <-t.in:
+		return msg, nil
+	case <-t.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+// WriteMessage never blocks: the Hub writes to recipients one after another,
+// so a stalled stream would hold up everyone else. A client whose buffer has
+// filled up is disconnected instead.
+func (t *sseTransport) WriteMessage(b []byte) error {
+	select {
+	case <-t.closed:
+		return net.ErrClosed
+	default:
+	}
+	select {
+	case t.out <- b:
+		return nil
+	default:
+		t.Close()
+		return errSSESlowClient
+	}
+}
+
+func (t *sseTransport) CloseWithCode(code int, reason string) error {
+	return t.WriteMessage(MustMarshal(WsMessage{
+		Type: TypeClose,
+		Data: MustMarshal(CloseData{Code: code, Reason: reason}),
+	}))
+}
+
+func (t *sseTransport) Close() error {
+	t.once.Do(func() { close(t.closed) })
+	return nil
+}
+
+func (t *sseTransport) deliver(msg []byte) error {
+	select {
+	case t.in <- msg:
+		return nil
+	case <-t.closed:
+		return net.ErrClosed
+	default:
+		return errSSESlowClient
+	}
+}
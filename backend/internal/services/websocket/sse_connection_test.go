@@ -0,0 +1,27 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSSESlowClientIsDisconnectedWithoutBlocking(t *testing.T) {
+	conn, stream := NewSSEConnection("test", "")
+
+	start := time.Now()
+	var err error
+	for i := 0; i <= sseBufferSize && err == nil; i++ {
+		err = conn.Send([]byte(`{}`))
+	}
+	if err == nil {
+		t.Fatal("send past a full buffer succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("sends blocked for %s", elapsed)
+	}
+	select {
+	case <-stream.Done():
+	default:
+		t.Fatal("slow client was not disconnected")
+	}
+}
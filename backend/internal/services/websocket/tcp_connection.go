@@ -0,0 +1,78 @@
+package websocket
+
+import (
+	"bufio"
+	"net"
+	"time"
+)
+
+// tcpTransport speaks newline-delimited JSON envelopes over a raw socket.
+type tcpTransport struct {
+	conn    net.Conn
+	scanner *bufio.Scanner
+}
+
+// NewTCPConnection wraps a TCP socket whose handshake has been read from
+// scanner. Later lines are read from the same scanner so nothing buffered
+// during the handshake is lost.
+func NewTCPConnection(conn net.Conn, scanner *bufio.Scanner, device string) *Connection {
+	return newConnection(&tcpTransport{conn: conn, scanner: scanner}, SessionInfo{
+		Device:    device,
+		UserAgent: "tcp " + conn.RemoteAddr().String(),
+		Transport: "tcp",
+	})
+}
+
+// NewTCPScanner returns a line scanner sized for envelopes.
+func NewTCPScanner(conn net.Conn) *bufio.Scanner {
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 4096), maxMessageSize)
+	return scanner
+}
+
+func (t *tcpTransport) ReadMessage() ([]byte, error) {
+	for t.scanner.Scan() {
+		line := t.scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		msg := make([]byte, len(line))
+		copy(msg, line)
+		return msg, nil
+	}
+	if err := t.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, net.ErrClosed
+}
+
+// WriteMessage writes one line. Any failure closes the connection: part of
+// the line may already be on the wire, and the next one would be appended to
+// it.
+func (t *tcpTransport) WriteMessage(b []byte) error {
+	if err := t.conn.SetWriteDeadline(time.Now().Add(10 * time.Second)); err != nil {
+		_ = t.conn.Close()
+		return err
+	}
+	line := make([]byte, 0, len(b)+1)
+	line = append(line, b...)
+	line = append(line, '\n')
+	if _, err := t.conn.Write(line); err != nil {
+		_ = t.conn.Close()
+		return err
+	}
+	return nil
+}
+
+// CloseWithCode sends a close envelope, the line protocol's stand-in for a
+// WebSocket close frame.
+func (t *tcpTransport) CloseWithCode(code int, reason string) error {
+	return t.WriteMessage(MustMarshal(WsMessage{
+		Type: TypeClose,
+		Data: MustMarshal(CloseData{Code: code, Reason: reason}),
+	}))
+}
+
+func (t *tcpTransport) Close() error {
+	return t.conn.Close()
+}
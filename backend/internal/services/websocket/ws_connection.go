@@ -1,30 +1,211 @@
 package websocket
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"log"
+	"sync"
+	"time"
 
 	"github.com/gofiber/websocket/v2"
 )
 
+// maxMessageSize bounds a single client message on every transport.
+const maxMessageSize = 1 << 20
+
+// CloseSessionTerminated is the close code sent when the user ends a session
+// from another device.
+const CloseSessionTerminated = 4001
+
+// SessionInfo describes the device behind a connection.
+type SessionInfo struct {
+	Id          string    `json:"id"`
+	Device      string    `json:"device"`
+	UserAgent   string    `json:"userAgent"`
+	Transport   string    `json:"transport"`
+	ConnectedAt time.Time `json:"connectedAt"`
+}
+
+// transport moves whole envelopes over one underlying connection.
+type transport interface {
+	ReadMessage() ([]byte, error)
+	WriteMessage(b []byte) error
+	CloseWithCode(code int, reason string) error
+	Close() error
+}
+
+// Connection is one client connection registered in the Hub, whatever
+// transport it arrived on. Writes are serialised so the Hub may send from
+// many goroutines.
 type Connection struct {
-	ws *websocket.Conn
+	t       transport
+	session SessionInfo
+	codec   Codec
+	writeMu sync.Mutex
 }
 
+func newConnection(t transport, session SessionInfo) *Connection {
+	session.Id = newSessionId()
+	session.ConnectedAt = time.Now()
+	return &Connection{t: t, session: session, codec: JSONCodec}
+}
+
+// NewConnection wraps a WebSocket, labelling it with the "device" query
+// parameter and the User-Agent header. The codec follows the negotiated
+// subprotocol.
 func NewConnection(c *websocket.Conn) *Connection {
-	return &Connection{ws: c}
+	codec := CodecForSubprotocol(c.Subprotocol())
+	conn := newConnection(&wsTransport{ws: c, binary: codec.Binary()}, SessionInfo{
+		Device:    c.Query("device"),
+		UserAgent: c.Headers("User-Agent"),
+		Transport: "websocket",
+	})
+	conn.codec = codec
+	c.SetReadLimit(maxMessageSize)
+	return conn
+}
+
+func (c *Connection) Session() SessionInfo {
+	return c.session
+}
+
+// Deliver queues an action posted out of band, for transports such as SSE
+// that cannot carry client messages themselves.
+func (c *Connection) Deliver(msg []byte) error {
+	d, ok := c.t.(interface{ deliver([]byte) error })
+	if !ok {
+		return ErrNotDeliverable
+	}
+	return d.deliver(msg)
 }
 
+// SetCompression applies permessage-deflate settings. It is a no-op for
+// transports other than WebSocket and for clients that did not negotiate the
+// extension.
+func (c *Connection) SetCompression(opts Compression) error {
+	t, ok := c.t.(*wsTransport)
+	if !ok || !opts.Enabled {
+		return nil
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if err := t.ws.SetCompressionLevel(opts.Level); err != nil {
+		return err
+	}
+	t.compress = true
+	t.threshold = opts.Threshold
+	return nil
+}
+
+func (c *Connection) Codec() Codec {
+	return c.codec
+}
+
+// Read returns the next client message as JSON, whatever codec it arrived in.
 func (c *Connection) Read() ([]byte, error) {
-	_, msg, err := c.ws.ReadMessage()
-	return msg, err
+	frame, err := c.t.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+	msg, err := c.codec.Decode(frame)
+	if err != nil {
+		// Undecodable frames reach the handler as-is and fail validation
+		// there, rather than dropping the connection.
+		return frame, nil
+	}
+	return msg, nil
 }
 
 func (c *Connection) Send(b []byte) error {
-	return c.ws.WriteMessage(websocket.TextMessage, b)
+	frame, err := c.codec.Encode(b)
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.t.WriteMessage(frame)
+}
+
+// SendFrame writes a shared frame. Transports that can write prepared
+// messages reuse the frame's wire bytes; the rest write its payload.
+func (c *Connection) SendFrame(f *Frame) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if p, ok := c.t.(interface{ writeFrame(*Frame) error }); ok {
+		return p.writeFrame(f)
+	}
+	return c.t.WriteMessage(f.Data())
 }
 
 func (c *Connection) Close() {
-	if err := c.ws.Close(); err != nil {
+	if err := c.t.Close(); err != nil {
 		log.Println("ws close err:", err)
 	}
-}
\ No newline at end of file
+}
+
+// CloseWithCode tells the client why it is being disconnected and then closes
+// the connection, which ends its read loop.
+func (c *Connection) CloseWithCode(code int, reason string) {
+	c.writeMu.Lock()
+	err := c.t.CloseWithCode(code, reason)
+	c.writeMu.Unlock()
+	if err != nil {
+		log.Println("ws close frame err:", err)
+	}
+	c.Close()
+}
+
+type wsTransport struct {
+	ws     *websocket.Conn
+	binary bool
+
+	compress  bool
+	threshold int
+}
+
+func (t *wsTransport) ReadMessage() ([]byte, error) {
+	_, msg, err := t.ws.ReadMessage()
+	return msg, err
+}
+
+func (t *wsTransport) WriteMessage(b []byte) error {
+	t.compressFor(len(b))
+	if t.binary {
+		return t.ws.WriteMessage(websocket.BinaryMessage, b)
+	}
+	return t.ws.WriteMessage(websocket.TextMessage, b)
+}
+
+// writeFrame writes a shared prepared message. Connections with the same
+// compression settings reuse one compressed copy of it.
+func (t *wsTransport) writeFrame(f *Frame) error {
+	pm, err := f.preparedMessage()
+	if err != nil {
+		return err
+	}
+	t.compressFor(len(f.Data()))
+	return t.ws.WritePreparedMessage(pm)
+}
+
+// compressFor turns compression on for the next message only if it reaches
+// the threshold. Callers hold the connection's write lock.
+func (t *wsTransport) compressFor(size int) {
+	if t.compress {
+		t.ws.EnableWriteCompression(size >= t.threshold)
+	}
+}
+
+func (t *wsTransport) CloseWithCode(code int, reason string) error {
+	msg := websocket.FormatCloseMessage(code, reason)
+	return t.ws.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+}
+
+func (t *wsTransport) Close() error {
+	return t.ws.Close()
+}
+
+func newSessionId() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -16,13 +16,29 @@ const (
 	TypeReactMessage     MessageType = "react_message"
 	TypeCreateRoom       MessageType = "create_room"
 	TypeJoinRoom         MessageType = "join_room"
+	TypeModeration       MessageType = "moderation"
+	TypeError            MessageType = "error"
+	TypeJoinRequest      MessageType = "join_request"
+	TypeJoinDecision     MessageType = "join_request_decision"
+	TypeRoomUpdated      MessageType = "room_updated"
+	TypeRoomArchived     MessageType = "room_archived"
+	TypeRoomDeleted      MessageType = "room_deleted"
+	TypeRoomPresence     MessageType = "room_presence"
+	TypeActivity         MessageType = "activity"
+	TypeSubscribe        MessageType = "subscribe"
+	TypeUnsubscribe      MessageType = "unsubscribe"
+	TypeAuth             MessageType = "auth"
+	TypeClose            MessageType = "close"
 )
 
 type UserStatus string
 
 const (
-	StatusOnline  UserStatus = "online"
-	StatusOffline UserStatus = "offline"
+	StatusOnline       UserStatus = "online"
+	StatusOffline      UserStatus = "offline"
+	StatusAway         UserStatus = "away"
+	StatusBusy         UserStatus = "busy"
+	StatusDoNotDisturb UserStatus = "dnd"
 )
 
 type WsMessage struct {
@@ -35,6 +51,12 @@ type UserPresenceData struct {
 	UserId  string             `json:"userId"`
 	Name    string             `json:"name"`
 	Profile domain.ProfileType `json:"profile,omitempty"`
+
+	// Status is the user's effective status: their chosen one, or away
+	// while every connection is idle.
+	Status          UserStatus `json:"status,omitempty"`
+	StatusText      string     `json:"statusText,omitempty"`
+	StatusExpiresAt *time.Time `json:"statusExpiresAt,omitempty"`
 }
 
 type UserOfflineData struct {
@@ -43,6 +65,28 @@ type UserOfflineData struct {
 
 type PresenceSnapshotData struct {
 	Users []UserPresenceData `json:"users"`
+	// Session identifies the receiving connection so clients can tell
+	// their own device apart in the session list.
+	Session *SessionInfo `json:"session,omitempty"`
+}
+
+// IncomingAuthData is the handshake line a TCP client sends before anything
+// else. Mode "test" mirrors the HTTP test-mode login.
+type IncomingAuthData struct {
+	Token  string `json:"token"`
+	Mode   string `json:"mode,omitempty"`
+	Device string `json:"device,omitempty"`
+}
+
+type AuthOkData struct {
+	UserId  string      `json:"userId"`
+	Session SessionInfo `json:"session"`
+}
+
+// CloseData tells line-protocol clients why the server is disconnecting them.
+type CloseData struct {
+	Code   int    `json:"code"`
+	Reason string `json:"reason"`
 }
 
 type IncomingTextData struct {
@@ -76,7 +120,11 @@ type OutgoingReactData struct {
 type IncomingCreateRoomData struct {
 	ChatName   string                 `json:"chatName"`
 	Background domain.BackgroundColor `json:"background"`
-	IsPublic   bool                   `json:"isPublic"`
+	// IsPublic defaults to true when absent, as clients predating
+	// visibility expect.
+	IsPublic   *bool                 `json:"isPublic,omitempty"`
+	Visibility domain.RoomVisibility `json:"visibility,omitempty"`
+	MemberIds  []string              `json:"memberIds,omitempty"`
 }
 
 type OutgoingCreateRoomData struct {
@@ -92,6 +140,17 @@ type IncomingJoinRoomData struct {
 	RoomId string `json:"roomId"`
 }
 
+// IncomingSubscribeData is used by subscribe and unsubscribe, which only
+// change what the connection receives, never room membership.
+type IncomingSubscribeData struct {
+	RoomId string `json:"roomId"`
+}
+
+type SubscriptionData struct {
+	RoomId     string `json:"roomId"`
+	Subscribed bool   `json:"subscribed"`
+}
+
 type RoomMemberJoinedData struct {
 	RoomId  string             `json:"roomId"`
 	UserId  string             `json:"userId"`
@@ -99,6 +158,98 @@ type RoomMemberJoinedData struct {
 	Profile domain.ProfileType `json:"profile,omitempty"`
 }
 
+// RoomPresenceData is sent with the user's effective status when their first
+// connection subscribes to a room or their status changes, and with offline
+// when their last connection leaves it.
+type RoomPresenceData struct {
+	RoomId     string             `json:"roomId"`
+	UserId     string             `json:"userId"`
+	Name       string             `json:"name,omitempty"`
+	Profile    domain.ProfileType `json:"profile,omitempty"`
+	StatusText string             `json:"statusText,omitempty"`
+}
+
+type RoomUpdatedData struct {
+	RoomId      string                 `json:"roomId"`
+	UpdatedBy   string                 `json:"updatedBy"`
+	RoomName    string                 `json:"roomName"`
+	Topic       string                 `json:"topic,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	AvatarUrl   string                 `json:"avatarUrl,omitempty"`
+	Tags        []string               `json:"tags,omitempty"`
+	Background  domain.BackgroundColor `json:"background"`
+
+	AnnouncementOnly bool `json:"announcementOnly"`
+	SlowModeSeconds  int  `json:"slowModeSeconds"`
+}
+
+type RoomArchivedData struct {
+	RoomId   string `json:"roomId"`
+	ActorId  string `json:"actorId"`
+	Archived bool   `json:"archived"`
+}
+
+type RoomDeletedData struct {
+	RoomId  string `json:"roomId"`
+	ActorId string `json:"actorId"`
+}
+
+type JoinRequestData struct {
+	RequestId string                   `json:"requestId"`
+	RoomId    string                   `json:"roomId"`
+	UserId    string                   `json:"userId"`
+	Name      string                   `json:"name,omitempty"`
+	Profile   domain.ProfileType       `json:"profile,omitempty"`
+	Status    domain.JoinRequestStatus `json:"status"`
+}
+
+type ModerationAction string
+
+const (
+	ModerationKick    ModerationAction = "kick"
+	ModerationBan     ModerationAction = "ban"
+	ModerationUnban   ModerationAction = "unban"
+	ModerationMute    ModerationAction = "mute"
+	ModerationUnmute  ModerationAction = "unmute"
+	ModerationPromote ModerationAction = "promote"
+	ModerationDemote  ModerationAction = "demote"
+)
+
+type ModerationData struct {
+	RoomId   string           `json:"roomId"`
+	Action   ModerationAction `json:"action"`
+	TargetId string           `json:"targetId"`
+	ActorId  string           `json:"actorId"`
+	Until    *time.Time       `json:"until,omitempty"`
+}
+
+type ErrorCode string
+
+const (
+	ErrCodeRoomNotFound ErrorCode = "room_not_found"
+	ErrCodeForbidden    ErrorCode = "forbidden"
+	ErrCodeBanned       ErrorCode = "banned"
+	ErrCodeMuted        ErrorCode = "muted"
+	ErrCodeArchived     ErrorCode = "room_archived"
+	ErrCodeAnnouncement ErrorCode = "announcement_only"
+	ErrCodeSlowMode     ErrorCode = "slow_mode"
+	ErrCodeUnauthorized ErrorCode = "unauthorized"
+	ErrCodeRateLimited  ErrorCode = "rate_limited"
+	ErrCodeInternal     ErrorCode = "internal"
+)
+
+// ErrorData is sent back to a single connection when one of its requests is
+// rejected.
+type ErrorData struct {
+	Code    ErrorCode   `json:"code"`
+	Message string      `json:"message"`
+	Request MessageType `json:"request,omitempty"`
+	RoomId  string      `json:"roomId,omitempty"`
+	RetryAt *time.Time  `json:"retryAt,omitempty"`
+	// RetryAfterMs is the remaining cooldown for rate-style rejections.
+	RetryAfterMs int64 `json:"retryAfterMs,omitempty"`
+}
+
 func MustMarshal(v any) []byte {
 	b, err := json.Marshal(v)
 	if err != nil {
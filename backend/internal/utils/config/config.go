@@ -7,12 +7,24 @@ import (
 // auth config
 type Config struct {
 	Port                   string
+	TCPPort                string
 	MongoURI               string
 	MongoDBName            string
 	FirebaseAccountKeyFile string
 	UserCollectionName     string
 	MassageCollectionName     string
 	RoomCollectionName     string
+	InviteCollectionName   string
+	JoinRequestCollectionName string
+	PreferenceCollectionName  string
+	AuditCollectionName       string
+	WSCompression          bool
+	WSCompressionLevel     int
+	WSCompressionThreshold int
+	WSRateLimit            bool
+	WSRateLimits           string
+	WSRateLimitStrikes     int
+	WSRateLimitCooldown    int
 }
 
 const (
@@ -20,16 +32,32 @@ const (
 	messageCollectionName = "message"
 	roomCollectionName = "rooms"
 	userCollectionName = "users"
+	inviteCollectionName = "room_invites"
+	joinRequestCollectionName = "join_requests"
+	preferenceCollectionName = "room_preferences"
+	auditCollectionName = "room_audit_log"
 )
 
 func LoadConfig() *Config {
 	return &Config{
 		Port:                   env.GetString("PORT", "8080"),
+		TCPPort:                env.GetString("TCP_PORT", "9090"),
 		MongoURI:               env.GetString("MONGO_URI", "mongodb://localhost:27017"),
 		MongoDBName:            dbName,
 		MassageCollectionName:  messageCollectionName,
 		RoomCollectionName:     roomCollectionName,
 		UserCollectionName:     userCollectionName,
+		InviteCollectionName:   inviteCollectionName,
+		JoinRequestCollectionName: joinRequestCollectionName,
+		PreferenceCollectionName:  preferenceCollectionName,
+		AuditCollectionName:       auditCollectionName,
 		FirebaseAccountKeyFile: env.GetString("FIREBASE_KEY_PATH", "firebase-key.json"),
+		WSCompression:          env.GetBool("WS_COMPRESSION", false),
+		WSCompressionLevel:     env.GetInt("WS_COMPRESSION_LEVEL", 1),
+		WSCompressionThreshold: env.GetInt("WS_COMPRESSION_THRESHOLD", 512),
+		WSRateLimit:            env.GetBool("WS_RATE_LIMIT", true),
+		WSRateLimits:           env.GetString("WS_RATE_LIMITS", ""),
+		WSRateLimitStrikes:     env.GetInt("WS_RATE_LIMIT_STRIKES", 10),
+		WSRateLimitCooldown:    env.GetInt("WS_RATE_LIMIT_COOLDOWN", 60),
 	}
 }